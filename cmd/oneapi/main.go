@@ -15,10 +15,12 @@ import (
 
 var (
 	flagDebug bool
+	flagSeed  string
 )
 
 func main() {
 	flag.BoolVar(&flagDebug, "debug", false, "enable debug mode")
+	flag.StringVar(&flagSeed, "seed", "", "path to a YAML or JSON seed file to load after schema creation")
 
 	flag.Usage = func() {
 		fmt.Printf("Usage: %s [flags] manifest-path\n", os.Args[0])
@@ -34,19 +36,40 @@ func main() {
 		log.Fatalf("Failed to load manifest: %v", err)
 	}
 
-	db, err := storage.NewSQLiteDB()
+	driverName := ""
+	var driverConfig storage.DriverConfig
+	if manifest.Server != nil && manifest.Server.Storage != nil {
+		driverName = manifest.Server.Storage.Driver
+		driverConfig = storage.DriverConfig{
+			DSN:     manifest.Server.Storage.DSN,
+			Options: manifest.Server.Storage.Options,
+		}
+	}
+	isSQLite := driverName == "" || driverName == "sqlite"
+	if isSQLite && driverConfig.DSN == "" && manifest.Server != nil && manifest.Server.Persist != nil {
+		driverConfig.DSN = *manifest.Server.Persist
+	}
+
+	driver, err := storage.DriverFor(driverName)
+	if err != nil {
+		log.Fatalf("Failed to resolve storage driver: %v", err)
+	}
+
+	db, err := driver.OpenDB(driverConfig)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.Close()
 
+	entityRegistry := storage.NewEntityRegistry()
+	entities := make(map[string]*storage.Entity)
 	repositories := make(map[string]storage.Repository)
 	for entityName, entityDef := range manifest.Entities {
-		entityRegistry := storage.NewEntityRegistry()
 		entityObj := entityRegistry.RegisterEntity(entityName, entityDef)
+		entities[entityName] = entityObj
 
 		// Create repository for entity
-		repo := storage.NewSQLiteRepository(db, entityObj)
+		repo := driver.NewRepository(db, entityObj)
 		repositories[entityName] = repo
 
 		// Create database schema for entity
@@ -55,12 +78,39 @@ func main() {
 		}
 	}
 
+	// Wire up belongs_to validation now that every entity's repository exists.
+	for _, entityObj := range entities {
+		entityObj.SetRelationResolver(func(target string, id int64) (bool, error) {
+			targetRepo, ok := repositories[target]
+			if !ok {
+				return false, fmt.Errorf("unknown related entity %q", target)
+			}
+			if _, err := targetRepo.FindByID(id); err != nil {
+				return false, nil
+			}
+			return true, nil
+		})
+	}
+
+	if err := loadSeeds(manifest.Seeds, repositories, entities); err != nil {
+		log.Fatalf("Failed to load manifest seed data: %v", err)
+	}
+	if flagSeed != "" {
+		fileSeeds, err := spec.LoadSeeds(flagSeed)
+		if err != nil {
+			log.Fatalf("Failed to read seed file: %v", err)
+		}
+		if err := loadSeeds(fileSeeds, repositories, entities); err != nil {
+			log.Fatalf("Failed to load seed data from %s: %v", flagSeed, err)
+		}
+	}
+
 	serverOpts, err := server.OptionsFromManifest(*manifest)
 	if err != nil {
 		log.Fatalf("Failed to get server options: %v", err)
 	}
 
-	srv := server.NewServer(*serverOpts, repositories)
+	srv := server.NewServer(*serverOpts, *manifest, repositories, entities)
 	go srv.Start()
 
 	stop := make(chan os.Signal, 1)
@@ -69,3 +119,31 @@ func main() {
 
 	fmt.Println("Shutting down server...")
 }
+
+// loadSeeds inserts each seed row into its entity's repository, via the same
+// Create path (and therefore the same validation and relationship checks) a
+// JSON:API POST would use. Entities are inserted in dependency order (belongs_to
+// targets before their dependents) rather than the seeds map's randomized
+// iteration order, so a belongs_to foreign key always finds its target
+// already created.
+func loadSeeds(seeds map[string][]map[string]interface{}, repositories map[string]storage.Repository, entities map[string]*storage.Entity) error {
+	for entityName := range seeds {
+		if _, ok := repositories[entityName]; !ok {
+			return fmt.Errorf("seed data references unknown entity %q", entityName)
+		}
+	}
+
+	for _, entityName := range storage.OrderByDependency(entities) {
+		rows, ok := seeds[entityName]
+		if !ok {
+			continue
+		}
+		repo := repositories[entityName]
+		for _, row := range rows {
+			if _, err := repo.Create(row); err != nil {
+				return fmt.Errorf("seeding %s: %w", entityName, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/certainty/oneapi/internal/spec"
+	"github.com/gofiber/fiber/v2"
+)
+
+const principalLocalsKey = "oneapi_principal"
+
+// adminScope gates the bulk export/import endpoints, which read and write
+// every entity regardless of its own per-entity scopes. It isn't tied to any
+// single entity, so it can't be declared via an EntityAuthConfig; a token
+// needs it added to its `scopes:` list explicitly (or to be unscoped) to use
+// those endpoints.
+const adminScope = "admin"
+
+// Principal is the resolved identity of an authenticated request, attached
+// to c.Locals by bearerAuthMiddleware.
+type Principal struct {
+	Token  string
+	Scopes map[string]bool
+}
+
+// allowsScope reports whether the principal may use scope. A nil Scopes set
+// means the token is unscoped and allows everything.
+func (p Principal) allowsScope(scope string) bool {
+	if p.Scopes == nil {
+		return true
+	}
+	return p.Scopes[scope]
+}
+
+func principalFromContext(c *fiber.Ctx) (Principal, bool) {
+	p, ok := c.Locals(principalLocalsKey).(Principal)
+	return p, ok
+}
+
+// tokenTable resolves a bearer token to its allowed scopes; a nil value
+// means the token is unscoped.
+type tokenTable map[string]map[string]bool
+
+// newTokenTable flattens an AuthConfig's bearer_token/tokens configuration
+// into a token lookup. A nil/empty table means the server has no auth
+// configured at all.
+func newTokenTable(auth *spec.AuthConfig) tokenTable {
+	if auth == nil {
+		return nil
+	}
+
+	table := make(tokenTable)
+	if auth.BearerToken != nil && auth.BearerToken.Token != "" {
+		table[auth.BearerToken.Token] = nil
+	}
+	for _, t := range auth.Tokens {
+		var scopes map[string]bool
+		if len(t.Scopes) > 0 {
+			scopes = make(map[string]bool, len(t.Scopes))
+			for _, s := range t.Scopes {
+				scopes[s] = true
+			}
+		}
+		table[t.Token] = scopes
+	}
+	return table
+}
+
+func (t tokenTable) principalFor(token string) (Principal, bool) {
+	scopes, ok := t[token]
+	if !ok {
+		return Principal{}, false
+	}
+	return Principal{Token: token, Scopes: scopes}, true
+}
+
+// bearerAuthMiddleware rejects requests missing a valid `Authorization:
+// Bearer <token>` header and attaches the resolved Principal to c.Locals. If
+// auth has no tokens configured at all, it's a no-op, so local development
+// needs no setup.
+func bearerAuthMiddleware(auth *spec.AuthConfig) fiber.Handler {
+	table := newTokenTable(auth)
+
+	return func(c *fiber.Ctx) error {
+		if len(table) == 0 {
+			return c.Next()
+		}
+
+		token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok || token == "" {
+			return fiber.NewError(fiber.StatusUnauthorized, "missing bearer token")
+		}
+
+		principal, ok := table.principalFor(token)
+		if !ok {
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid bearer token")
+		}
+
+		c.Locals(principalLocalsKey, principal)
+		return c.Next()
+	}
+}
+
+// requireScopes rejects the request with 403 unless the authenticated
+// principal holds every scope in scopes. If scopes is empty, the request is
+// allowed through unconditionally. An entity declaring scopes requires a
+// principal to be attached: if the top-level `auth` block is missing (so
+// bearerAuthMiddleware never attaches one), the scopes can never be
+// satisfied, rather than being silently treated as unprotected.
+func requireScopes(scopes []string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if len(scopes) == 0 {
+			return c.Next()
+		}
+
+		principal, ok := principalFromContext(c)
+		if !ok {
+			return fiber.NewError(fiber.StatusUnauthorized, "entity requires scoped auth but the server has no auth configured")
+		}
+
+		for _, scope := range scopes {
+			if !principal.allowsScope(scope) {
+				return fiber.NewError(fiber.StatusForbidden, fmt.Sprintf("missing required scope %q", scope))
+			}
+		}
+		return c.Next()
+	}
+}
+
+// entityScopes returns the scopes def.Auth requires for verb ("read",
+// "write" or "delete"), or nil if def has no auth configuration.
+func entityScopes(def spec.EntityDef, verb string) []string {
+	if def.Auth == nil {
+		return nil
+	}
+	switch verb {
+	case "read":
+		return def.Auth.Read
+	case "write":
+		return def.Auth.Write
+	case "delete":
+		return def.Auth.Delete
+	default:
+		return nil
+	}
+}
@@ -1,49 +1,119 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
-	"github.com/certainty/oneapi/internal/storage"
-	"github.com/gofiber/fiber/v2"
 	"strconv"
 	"strings"
+
+	"github.com/certainty/oneapi/internal/storage"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
 )
 
 type JSONAPIHandler struct {
-	repo storage.Repository
+	entityName string
+	entity     *storage.Entity
+	repo       storage.Repository
+	entities   map[string]*storage.Entity
+	registry   storage.RelationRegistry
 }
 
-func NewJSONAPIHandler(repo storage.Repository) *JSONAPIHandler {
+func NewJSONAPIHandler(entityName string, entity *storage.Entity, repo storage.Repository, entities map[string]*storage.Entity, registry storage.RelationRegistry) *JSONAPIHandler {
 	return &JSONAPIHandler{
-		repo: repo,
+		entityName: entityName,
+		entity:     entity,
+		repo:       repo,
+		entities:   entities,
+		registry:   registry,
 	}
 }
 
-// List handles GET requests for listing entities
+// List handles GET requests for listing entities, supporting JSON:API
+// pagination, filtering (?filter[field]=value, ?filter[field][op]=value),
+// sorting (?sort=-created_at,name) and sparse fieldsets
+// (?fields[entity]=name,email).
 func (h *JSONAPIHandler) List(c *fiber.Ctx) error {
-	// Parse pagination parameters
-	page, _ := strconv.Atoi(c.Query("page[number]", "1"))
-	pageSize, _ := strconv.Atoi(c.Query("page[size]", "10"))
+	q, err := parseListQuery(c, h.entityName, h.entity)
+	if err != nil {
+		return err
+	}
 
-	// Get data from repository
-	data, total, err := h.repo.List(page, pageSize)
+	data, total, err := h.repo.List(q)
 	if err != nil {
 		return err
 	}
 
+	included, err := h.includedFor(data, c.Query("include"))
+	if err != nil {
+		return err
+	}
+
+	pageSize := q.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
 	// Format as JSONapi response
 	result := map[string]interface{}{
-		"data": formatJSONapiData(data),
+		"data": h.formatJSONapiData(data),
 		"meta": map[string]interface{}{
 			"total":       total,
-			"page":        page,
+			"page":        q.Page,
 			"page_size":   pageSize,
 			"total_pages": (total + pageSize - 1) / pageSize,
 		},
+		"links": h.paginationLinks(c, q, total),
+	}
+	if len(included) > 0 {
+		result["included"] = included
 	}
 
 	return c.JSON(result)
 }
 
+// paginationLinks builds the self/first/prev/next/last links for a List
+// response, preserving the request's filter/sort/fields query parameters and
+// only varying page[number].
+func (h *JSONAPIHandler) paginationLinks(c *fiber.Ctx, q storage.Query, total int) map[string]interface{} {
+	pageSize := q.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	page := q.Page
+	if page < 1 {
+		page = 1
+	}
+
+	totalPages := (total + pageSize - 1) / pageSize
+	if totalPages < 1 {
+		totalPages = 1
+	}
+
+	links := map[string]interface{}{
+		"self":  pageLink(c, page),
+		"first": pageLink(c, 1),
+		"last":  pageLink(c, totalPages),
+	}
+	if page > 1 {
+		links["prev"] = pageLink(c, page-1)
+	}
+	if page < totalPages {
+		links["next"] = pageLink(c, page+1)
+	}
+	return links
+}
+
+// pageLink renders the current request's path and query string with
+// page[number] replaced by page.
+func pageLink(c *fiber.Ctx, page int) string {
+	args := fasthttp.AcquireArgs()
+	defer fasthttp.ReleaseArgs(args)
+	c.Context().QueryArgs().CopyTo(args)
+	args.Set("page[number]", strconv.Itoa(page))
+	return c.Path() + "?" + args.String()
+}
+
 func (h *JSONAPIHandler) Get(c *fiber.Ctx) error {
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
 	if err != nil {
@@ -55,25 +125,33 @@ func (h *JSONAPIHandler) Get(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusNotFound, err.Error())
 	}
 
-	return c.JSON(map[string]interface{}{
-		"data": formatJSONapiResource(data),
-	})
+	included, err := h.includedFor([]map[string]interface{}{data}, c.Query("include"))
+	if err != nil {
+		return err
+	}
+
+	result := map[string]interface{}{
+		"data": h.formatJSONapiResource(data),
+	}
+	if len(included) > 0 {
+		result["included"] = included
+	}
+
+	return c.JSON(result)
 }
 
 // Create handles POST requests to create entities
 func (h *JSONAPIHandler) Create(c *fiber.Ctx) error {
-	var request struct {
-		Data struct {
-			Attributes map[string]interface{} `json:"attributes"`
-		} `json:"data"`
-	}
+	var request jsonAPIRequest
 
 	if err := c.BodyParser(&request); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	// Extract attributes
-	data := request.Data.Attributes
+	data, err := h.mergeRelationships(request.Data.Attributes, request.Data.Relationships)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
 
 	// Create in repository
 	id, err := h.repo.Create(data)
@@ -92,7 +170,7 @@ func (h *JSONAPIHandler) Create(c *fiber.Ctx) error {
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(map[string]interface{}{
-		"data": formatJSONapiResource(entity),
+		"data": h.formatJSONapiResource(entity),
 	})
 }
 
@@ -103,18 +181,16 @@ func (h *JSONAPIHandler) Update(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid ID format")
 	}
 
-	var request struct {
-		Data struct {
-			Attributes map[string]interface{} `json:"attributes"`
-		} `json:"data"`
-	}
+	var request jsonAPIRequest
 
 	if err := c.BodyParser(&request); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	// Extract attributes
-	data := request.Data.Attributes
+	data, err := h.mergeRelationships(request.Data.Attributes, request.Data.Relationships)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
 
 	// Update in repository
 	if err := h.repo.Update(id, data); err != nil {
@@ -132,7 +208,7 @@ func (h *JSONAPIHandler) Update(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(map[string]interface{}{
-		"data": formatJSONapiResource(entity),
+		"data": h.formatJSONapiResource(entity),
 	})
 }
 
@@ -150,30 +226,305 @@ func (h *JSONAPIHandler) Delete(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusNoContent).Send(nil)
 }
 
+// GetRelationship handles GET /{entity}/{id}/relationships/{rel}, returning
+// the resource identifier (belongs_to) or array of identifiers (has_many)
+// for the relationship.
+func (h *JSONAPIHandler) GetRelationship(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid ID format")
+	}
+
+	relName := c.Params("rel")
+	rel, ok := h.entity.Relations[relName]
+	if !ok {
+		return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("unknown relationship %q", relName))
+	}
+
+	related, err := h.repo.FindRelated(id, relName, h.registry)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if rel.Kind == storage.BelongsTo {
+		if len(related) == 0 {
+			return c.JSON(map[string]interface{}{"data": nil})
+		}
+		return c.JSON(map[string]interface{}{"data": resourceIdentifier(rel.Target, related[0])})
+	}
+
+	identifiers := make([]map[string]interface{}, len(related))
+	for i, row := range related {
+		identifiers[i] = resourceIdentifier(rel.Target, row)
+	}
+	return c.JSON(map[string]interface{}{"data": identifiers})
+}
+
+// ReplaceRelationship handles PATCH /{entity}/{id}/relationships/{rel},
+// replacing the relationship with the resource identifier(s) given in the
+// request body's `data`.
+func (h *JSONAPIHandler) ReplaceRelationship(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid ID format")
+	}
+
+	relName := c.Params("rel")
+	if _, ok := h.entity.Relations[relName]; !ok {
+		return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("unknown relationship %q", relName))
+	}
+
+	var request relationshipRequest
+	if err := c.BodyParser(&request); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	targetIDs, err := request.Data.ids()
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := h.repo.ReplaceRelated(id, relName, targetIDs, h.registry); err != nil {
+		if strings.Contains(err.Error(), "validation failed") {
+			return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+		}
+		return err
+	}
+
+	return h.GetRelationship(c)
+}
+
+// includedFor resolves ?include=a,b into a deduplicated, flattened top-level
+// `included` array for the given primary resources.
+func (h *JSONAPIHandler) includedFor(primary []map[string]interface{}, includeParam string) ([]map[string]interface{}, error) {
+	if includeParam == "" {
+		return nil, nil
+	}
+
+	var included []map[string]interface{}
+	seen := map[string]bool{}
+
+	for _, relName := range strings.Split(includeParam, ",") {
+		relName = strings.TrimSpace(relName)
+		rel, ok := h.entity.Relations[relName]
+		if !ok {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("cannot include unknown relationship %q", relName))
+		}
+
+		targetEntity, ok := h.entities[rel.Target]
+		if !ok {
+			return nil, fmt.Errorf("unknown related entity %q", rel.Target)
+		}
+
+		for _, row := range primary {
+			id, ok := row["id"].(int64)
+			if !ok {
+				continue
+			}
+
+			related, err := h.repo.FindRelated(id, relName, h.registry)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, relatedRow := range related {
+				resource := formatJSONapiResourceFor(rel.Target, targetEntity, relatedRow)
+				key := fmt.Sprintf("%s:%v", resource["type"], resource["id"])
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				included = append(included, resource)
+			}
+		}
+	}
+
+	return included, nil
+}
+
+// mergeRelationships folds a JSON:API `relationships` object's belongs_to
+// linkage into the flat attributes map the storage layer works with, as
+// "<relation>_id" keys.
+func (h *JSONAPIHandler) mergeRelationships(attributes map[string]interface{}, relationships map[string]relationshipPayload) (map[string]interface{}, error) {
+	data := make(map[string]interface{}, len(attributes))
+	for k, v := range attributes {
+		data[k] = v
+	}
+
+	for relName, payload := range relationships {
+		rel, ok := h.entity.Relations[relName]
+		if !ok {
+			return nil, fmt.Errorf("unknown relationship %q", relName)
+		}
+		if rel.Kind != storage.BelongsTo {
+			return nil, fmt.Errorf("relationship %q must be set via /%s/{id}/relationships/%s", relName, h.entityName, relName)
+		}
+
+		ids, err := payload.Data.ids()
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			data[relName+"_id"] = nil
+			continue
+		}
+		data[relName+"_id"] = ids[0]
+	}
+
+	return data, nil
+}
+
 // Helper functions to format data in JSONapi format
-func formatJSONapiData(data []map[string]interface{}) []map[string]interface{} {
+
+func (h *JSONAPIHandler) formatJSONapiData(data []map[string]interface{}) []map[string]interface{} {
 	result := make([]map[string]interface{}, len(data))
 	for i, item := range data {
-		result[i] = formatJSONapiResource(item)
+		result[i] = h.formatJSONapiResource(item)
 	}
 	return result
 }
 
-func formatJSONapiResource(data map[string]interface{}) map[string]interface{} {
+func (h *JSONAPIHandler) formatJSONapiResource(data map[string]interface{}) map[string]interface{} {
+	return formatJSONapiResourceFor(h.entityName, h.entity, data)
+}
+
+// formatJSONapiResourceFor renders a raw repository row as a JSON:API
+// resource object, stripping relation foreign keys out of `attributes` and
+// into a `relationships` object with `links` per relation; only belongs_to
+// relations also carry `data`.
+func formatJSONapiResourceFor(entityName string, entity *storage.Entity, data map[string]interface{}) map[string]interface{} {
 	id, _ := data["id"].(int64)
 	idStr := fmt.Sprintf("%d", id)
 
-	// Remove id from attributes
 	attributes := make(map[string]interface{})
 	for k, v := range data {
-		if k != "id" {
-			attributes[k] = v
+		if k == "id" {
+			continue
 		}
+		if _, isForeignKey := entity.Relations[strings.TrimSuffix(k, "_id")]; isForeignKey && strings.HasSuffix(k, "_id") {
+			continue
+		}
+		attributes[k] = v
 	}
 
-	return map[string]interface{}{
+	resource := map[string]interface{}{
 		"id":         idStr,
-		"type":       "entity", // This should be dynamically determined based on entity type
+		"type":       entityName,
 		"attributes": attributes,
 	}
+
+	if len(entity.Relations) > 0 {
+		relationships := make(map[string]interface{}, len(entity.Relations))
+		for relName, rel := range entity.Relations {
+			// Only "self" is advertised: there's no GET /{entity}/{id}/{rel}
+			// route for a "related" link to point at, only
+			// /{entity}/{id}/relationships/{rel}.
+			links := map[string]interface{}{
+				"self": fmt.Sprintf("/%s/%s/relationships/%s", entityName, idStr, relName),
+			}
+
+			relationship := map[string]interface{}{"links": links}
+			if rel.Kind == storage.BelongsTo {
+				if fk, ok := data[relName+"_id"]; ok && fk != nil {
+					fkID, err := toIDString(fk)
+					if err == nil {
+						relationship["data"] = map[string]interface{}{"type": rel.Target, "id": fkID}
+					}
+				} else {
+					relationship["data"] = nil
+				}
+			}
+
+			relationships[relName] = relationship
+		}
+		resource["relationships"] = relationships
+	}
+
+	return resource
+}
+
+func resourceIdentifier(entityType string, row map[string]interface{}) map[string]interface{} {
+	id, _ := row["id"].(int64)
+	return map[string]interface{}{"type": entityType, "id": fmt.Sprintf("%d", id)}
+}
+
+func toIDString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case int64:
+		return fmt.Sprintf("%d", v), nil
+	case int:
+		return fmt.Sprintf("%d", v), nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("cannot format %T as an id", value)
+	}
+}
+
+// jsonAPIRequest is the JSON:API request envelope accepted by Create/Update.
+type jsonAPIRequest struct {
+	Data struct {
+		Type          string                        `json:"type"`
+		Attributes    map[string]interface{}         `json:"attributes"`
+		Relationships map[string]relationshipPayload `json:"relationships"`
+	} `json:"data"`
+}
+
+type relationshipPayload struct {
+	Data relationshipData `json:"data"`
+}
+
+// relationshipRequest is the envelope accepted by ReplaceRelationship.
+type relationshipRequest struct {
+	Data relationshipData `json:"data"`
+}
+
+// relationshipData holds either a single resource identifier (belongs_to) or
+// an array of them (has_many); BodyParser populates whichever arrived.
+type relationshipData struct {
+	one  *resourceIdentifierPayload
+	many []resourceIdentifierPayload
+}
+
+type resourceIdentifierPayload struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+func (d *relationshipData) UnmarshalJSON(b []byte) error {
+	trimmed := strings.TrimSpace(string(b))
+	if trimmed == "null" {
+		*d = relationshipData{}
+		return nil
+	}
+	if strings.HasPrefix(trimmed, "[") {
+		return json.Unmarshal(b, &d.many)
+	}
+
+	var one resourceIdentifierPayload
+	if err := json.Unmarshal(b, &one); err != nil {
+		return err
+	}
+	d.one = &one
+	return nil
+}
+
+func (d relationshipData) ids() ([]int64, error) {
+	if d.one != nil {
+		id, err := strconv.ParseInt(d.one.ID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q in relationship data", d.one.ID)
+		}
+		return []int64{id}, nil
+	}
+
+	ids := make([]int64, 0, len(d.many))
+	for _, item := range d.many {
+		id, err := strconv.ParseInt(item.ID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q in relationship data", item.ID)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
 }
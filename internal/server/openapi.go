@@ -0,0 +1,381 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"unicode"
+
+	"github.com/certainty/oneapi/internal/spec"
+)
+
+// buildOpenAPISpec generates an OpenAPI 3.0 document describing the JSON:API
+// surface exposed for every entity in the manifest: CRUD paths, JSON:API
+// request/response envelopes, and a components.schemas entry per entity.
+func buildOpenAPISpec(manifest spec.Manifest, opts Options) map[string]any {
+	entityNames := make([]string, 0, len(manifest.Entities))
+	for name := range manifest.Entities {
+		entityNames = append(entityNames, name)
+	}
+	sort.Strings(entityNames)
+
+	paths := map[string]any{}
+	schemas := map[string]any{
+		"Error": errorSchema(),
+	}
+
+	for _, name := range entityNames {
+		def := manifest.Entities[name]
+		schemas[schemaName(name)] = entityAttributesSchema(def)
+		schemas[resourceSchemaName(name)] = resourceSchema(name)
+
+		paths[fmt.Sprintf("/%s", name)] = collectionPathItem(name)
+		paths[fmt.Sprintf("/%s/{id}", name)] = memberPathItem(name)
+	}
+
+	return map[string]any{
+		"openapi": "3.0.0",
+		"info": map[string]any{
+			"title":   opts.APIName,
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+		},
+	}
+}
+
+// schemaName maps an entity name (as used in the manifest and in URLs) to the
+// PascalCase name used for its components.schemas entry.
+func schemaName(entityName string) string {
+	return capitalize(entityName)
+}
+
+func resourceSchemaName(entityName string) string {
+	return capitalize(entityName) + "Resource"
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// fieldSchema maps a manifest FieldDef's type/variants onto an OpenAPI schema.
+func fieldSchema(field spec.FieldDef) map[string]any {
+	switch field.Type {
+	case "string":
+		return map[string]any{"type": "string"}
+	case "int":
+		return map[string]any{"type": "integer", "format": "int64"}
+	case "double":
+		return map[string]any{"type": "number", "format": "double"}
+	case "bool":
+		return map[string]any{"type": "boolean"}
+	case "enum":
+		return map[string]any{"type": "string", "enum": field.Variants}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+// entityAttributesSchema builds the schema for an entity's attributes object,
+// i.e. the payload that sits under `data.attributes` in JSON:API requests and
+// responses.
+func entityAttributesSchema(def spec.EntityDef) map[string]any {
+	fieldNames := make([]string, 0, len(def.Fields))
+	for name := range def.Fields {
+		fieldNames = append(fieldNames, name)
+	}
+	sort.Strings(fieldNames)
+
+	properties := map[string]any{}
+	var required []string
+	for _, name := range fieldNames {
+		field := def.Fields[name]
+		if field.Type == "relation" {
+			continue
+		}
+		properties[name] = fieldSchema(field)
+		if field.Required {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// resourceSchema builds the JSON:API resource object schema for an entity:
+// {id, type, attributes}.
+func resourceSchema(entityName string) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id":   map[string]any{"type": "string"},
+			"type": map[string]any{"type": "string", "enum": []string{entityName}},
+			"attributes": map[string]any{
+				"$ref": refTo(schemaName(entityName)),
+			},
+		},
+		"required": []string{"id", "type", "attributes"},
+	}
+}
+
+func refTo(schema string) string {
+	return fmt.Sprintf("#/components/schemas/%s", schema)
+}
+
+func errorSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"errors": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"status": map[string]any{"type": "string"},
+						"title":  map[string]any{"type": "string"},
+						"detail": map[string]any{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func errorResponses() map[string]any {
+	errorContent := map[string]any{
+		"application/json": map[string]any{
+			"schema": map[string]any{"$ref": refTo("Error")},
+		},
+	}
+	return map[string]any{
+		"400": map[string]any{"description": "Invalid request", "content": errorContent},
+		"404": map[string]any{"description": "Not found", "content": errorContent},
+		"422": map[string]any{"description": "Validation failed", "content": errorContent},
+	}
+}
+
+func pageNumberParam() map[string]any {
+	return map[string]any{
+		"name":     "page[number]",
+		"in":       "query",
+		"required": false,
+		"schema":   map[string]any{"type": "integer", "default": 1},
+	}
+}
+
+func pageSizeParam() map[string]any {
+	return map[string]any{
+		"name":     "page[size]",
+		"in":       "query",
+		"required": false,
+		"schema":   map[string]any{"type": "integer", "default": 10},
+	}
+}
+
+func sortParam() map[string]any {
+	return map[string]any{
+		"name":        "sort",
+		"in":          "query",
+		"required":    false,
+		"description": "Comma-separated field names; prefix a field with \"-\" to sort descending.",
+		"schema":      map[string]any{"type": "string"},
+	}
+}
+
+func idParam() map[string]any {
+	return map[string]any{
+		"name":     "id",
+		"in":       "path",
+		"required": true,
+		"schema":   map[string]any{"type": "integer", "format": "int64"},
+	}
+}
+
+// collectionPathItem builds the GET (list) / POST (create) operations for
+// /{entity}.
+func collectionPathItem(entityName string) map[string]any {
+	resourceRef := map[string]any{"$ref": refTo(resourceSchemaName(entityName))}
+
+	listResponse := map[string]any{
+		"description": "A page of " + entityName,
+		"content": map[string]any{
+			"application/json": map[string]any{
+				"schema": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"data": map[string]any{"type": "array", "items": resourceRef},
+						"meta": map[string]any{"type": "object"},
+					},
+				},
+			},
+		},
+	}
+
+	createRequest := map[string]any{
+		"required": true,
+		"content": map[string]any{
+			"application/json": map[string]any{
+				"schema": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"data": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"type":       map[string]any{"type": "string", "enum": []string{entityName}},
+								"attributes": map[string]any{"$ref": refTo(schemaName(entityName))},
+							},
+							"required": []string{"attributes"},
+						},
+					},
+					"required": []string{"data"},
+				},
+			},
+		},
+	}
+
+	createResponse := map[string]any{
+		"description": "The created " + entityName,
+		"content": map[string]any{
+			"application/json": map[string]any{
+				"schema": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"data": resourceRef},
+				},
+			},
+		},
+	}
+
+	responses := map[string]any{"200": listResponse}
+	for status, resp := range errorResponses() {
+		responses[status] = resp
+	}
+	createResponses := map[string]any{"201": createResponse}
+	for status, resp := range errorResponses() {
+		createResponses[status] = resp
+	}
+
+	return map[string]any{
+		"get": map[string]any{
+			"summary":    "List " + entityName,
+			"operationId": "list" + schemaName(entityName),
+			"parameters": []map[string]any{pageNumberParam(), pageSizeParam(), sortParam()},
+			"responses":  responses,
+		},
+		"post": map[string]any{
+			"summary":     "Create " + entityName,
+			"operationId": "create" + schemaName(entityName),
+			"requestBody": createRequest,
+			"responses":   createResponses,
+		},
+	}
+}
+
+// memberPathItem builds the GET / PATCH / DELETE operations for
+// /{entity}/{id}.
+func memberPathItem(entityName string) map[string]any {
+	resourceRef := map[string]any{"$ref": refTo(resourceSchemaName(entityName))}
+
+	getResponse := map[string]any{
+		"description": "The requested " + entityName,
+		"content": map[string]any{
+			"application/json": map[string]any{
+				"schema":     map[string]any{"type": "object", "properties": map[string]any{"data": resourceRef}},
+			},
+		},
+	}
+	getResponses := map[string]any{"200": getResponse}
+	for status, resp := range errorResponses() {
+		getResponses[status] = resp
+	}
+
+	updateRequest := map[string]any{
+		"required": true,
+		"content": map[string]any{
+			"application/json": map[string]any{
+				"schema": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"data": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"attributes": map[string]any{"$ref": refTo(schemaName(entityName))},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	updateResponses := map[string]any{"200": getResponse}
+	for status, resp := range errorResponses() {
+		updateResponses[status] = resp
+	}
+
+	deleteResponses := map[string]any{
+		"204": map[string]any{"description": "Deleted"},
+	}
+	for status, resp := range errorResponses() {
+		deleteResponses[status] = resp
+	}
+
+	return map[string]any{
+		"get": map[string]any{
+			"summary":     "Get a single " + entityName,
+			"operationId": "get" + schemaName(entityName),
+			"parameters":  []map[string]any{idParam()},
+			"responses":   getResponses,
+		},
+		"patch": map[string]any{
+			"summary":     "Update a " + entityName,
+			"operationId": "update" + schemaName(entityName),
+			"parameters":  []map[string]any{idParam()},
+			"requestBody": updateRequest,
+			"responses":   updateResponses,
+		},
+		"delete": map[string]any{
+			"summary":     "Delete a " + entityName,
+			"operationId": "delete" + schemaName(entityName),
+			"parameters":  []map[string]any{idParam()},
+			"responses":   deleteResponses,
+		},
+	}
+}
+
+// swaggerUIHTML renders the Swagger UI page pointed at the given docs prefix.
+func swaggerUIHTML(docsPrefix string) string {
+	return `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <title>API Docs</title>
+    <link rel="stylesheet" href="https://cdnjs.cloudflare.com/ajax/libs/swagger-ui/5.20.1/swagger-ui.min.css">
+</head>
+<body>
+    <div id="swagger-ui"></div>
+    <script src="https://cdnjs.cloudflare.com/ajax/libs/swagger-ui/5.20.1/swagger-ui-bundle.min.js"></script>
+    <script src="https://cdnjs.cloudflare.com/ajax/libs/swagger-ui/5.20.1/swagger-ui-standalone-preset.min.js"></script>
+    <script>
+        window.onload = function() {
+            const ui = SwaggerUIBundle({
+                url: "` + docsPrefix + `/swagger.json",
+                dom_id: '#swagger-ui',
+                presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+                layout: "StandaloneLayout"
+            });
+        };
+    </script>
+</body>
+</html>`
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/certainty/oneapi/internal/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseListQuery parses the JSON:API query conventions List supports:
+// page[number]/page[size], filter[field]/filter[field][op], sort=, and
+// fields[entity] for sparse fieldsets. Filter, sort and fieldset names are
+// validated against entity so the repository layer never sees an unknown
+// column name.
+func parseListQuery(c *fiber.Ctx, entityName string, entity *storage.Entity) (storage.Query, error) {
+	q := storage.Query{
+		Page:     queryInt(c, "page[number]", 1),
+		PageSize: queryInt(c, "page[size]", 10),
+	}
+
+	fieldsKey := fmt.Sprintf("fields[%s]", entityName)
+
+	var parseErr error
+	c.Context().QueryArgs().VisitAll(func(rawKey, rawValue []byte) {
+		if parseErr != nil {
+			return
+		}
+
+		key := string(rawKey)
+		value := string(rawValue)
+
+		switch {
+		case strings.HasPrefix(key, "filter["):
+			filter, err := parseFilterKey(key, value)
+			if err != nil {
+				parseErr = err
+				return
+			}
+			if !isFilterableField(entity, filter.Field) {
+				parseErr = fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("cannot filter on unknown field %q", filter.Field))
+				return
+			}
+			q.Filters = append(q.Filters, filter)
+
+		case key == "sort":
+			for _, term := range strings.Split(value, ",") {
+				term = strings.TrimSpace(term)
+				if term == "" {
+					continue
+				}
+				s := storage.Sort{Field: term}
+				if strings.HasPrefix(term, "-") {
+					s.Descending = true
+					s.Field = strings.TrimPrefix(term, "-")
+				}
+				if !isFilterableField(entity, s.Field) {
+					parseErr = fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("cannot sort on unknown field %q", s.Field))
+					return
+				}
+				q.Sorts = append(q.Sorts, s)
+			}
+
+		case key == fieldsKey:
+			for _, field := range strings.Split(value, ",") {
+				field = strings.TrimSpace(field)
+				if field == "" {
+					continue
+				}
+				if !isFilterableField(entity, field) {
+					parseErr = fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("cannot select unknown field %q", field))
+					return
+				}
+				q.Fields = append(q.Fields, field)
+			}
+		}
+	})
+
+	return q, parseErr
+}
+
+func queryInt(c *fiber.Ctx, key string, def int) int {
+	v, err := strconv.Atoi(c.Query(key, strconv.Itoa(def)))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// parseFilterKey parses "filter[field]" or "filter[field][op]" into a
+// storage.Filter, defaulting the operator to "eq".
+func parseFilterKey(key, value string) (storage.Filter, error) {
+	inner := strings.TrimSuffix(strings.TrimPrefix(key, "filter["), "]")
+	parts := strings.SplitN(inner, "][", 2)
+
+	field := parts[0]
+	op := storage.FilterEq
+	if len(parts) == 2 {
+		op = storage.FilterOp(parts[1])
+	}
+	if !validFilterOps[op] {
+		return storage.Filter{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("unsupported filter operator %q", op))
+	}
+
+	return storage.Filter{Field: field, Op: op, Value: value}, nil
+}
+
+var validFilterOps = map[storage.FilterOp]bool{
+	storage.FilterEq:   true,
+	storage.FilterGt:   true,
+	storage.FilterGte:  true,
+	storage.FilterLt:   true,
+	storage.FilterLte:  true,
+	storage.FilterLike: true,
+}
+
+// isFilterableField reports whether field is a real scalar column on entity,
+// or a belongs_to foreign key column, and so safe to reference in a
+// filter/sort/fields query parameter.
+func isFilterableField(entity *storage.Entity, field string) bool {
+	if field == "id" {
+		return true
+	}
+	if f, ok := entity.Fields[field]; ok {
+		return f.Type != "relation"
+	}
+	if rel, ok := entity.Relations[strings.TrimSuffix(field, "_id")]; ok && strings.HasSuffix(field, "_id") {
+		return rel.Kind == storage.BelongsTo
+	}
+	return false
+}
@@ -1,10 +1,10 @@
 package server
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/certainty/oneapi/internal/spec"
 	"github.com/certainty/oneapi/internal/storage"
@@ -18,16 +18,22 @@ type Options struct {
 	HealthCheckPath string
 	APIDocsPrefix   string
 	APIDocsUIPath   string
+
+	// StrictValidation, when true, also validates each response payload
+	// against the entity schema before it's sent, failing the request
+	// instead of returning data that doesn't match its own schema.
+	StrictValidation bool
 }
 
 func NewOptions() *Options {
 	return &Options{
-		APIName:         "OneAPI",
-		Port:            9090,
-		PathPrefix:      "/api",
-		APIDocsUIPath:   "/api/docs",
-		HealthCheckPath: "/_oneapi/health",
-		APIDocsPrefix:   "/_oneapi/docs",
+		APIName:          "OneAPI",
+		Port:             9090,
+		PathPrefix:       "/api",
+		APIDocsUIPath:    "/api/docs",
+		HealthCheckPath:  "/_oneapi/health",
+		APIDocsPrefix:    "/_oneapi/docs",
+		StrictValidation: true,
 	}
 }
 
@@ -46,25 +52,52 @@ func OptionsFromManifest(manifest spec.Manifest) (*Options, error) {
 		if manifest.Server.APIDocsUIPath != nil {
 			opts.APIDocsUIPath = *manifest.Server.APIDocsUIPath
 		}
+		if manifest.Server.StrictValidation != nil {
+			opts.StrictValidation = *manifest.Server.StrictValidation
+		}
 	}
 	return opts, nil
 }
 
 type Server struct {
 	options      Options
+	manifest     spec.Manifest
 	jsonAPI      *JSONAPIHandler
 	repositories map[string]storage.Repository
+	entities     map[string]*storage.Entity
 }
 
-func NewServer(options Options, repositories map[string]storage.Repository) *Server {
+func NewServer(options Options, manifest spec.Manifest, repositories map[string]storage.Repository, entities map[string]*storage.Entity) *Server {
 
 	return &Server{
 		options:      options,
+		manifest:     manifest,
 		repositories: repositories,
+		entities:     entities,
 	}
 }
 
+// repositorySet implements storage.RelationRegistry over a Server's
+// repositories, so a Repository can look up its related entities' repos
+// without holding direct references to them.
+type repositorySet map[string]storage.Repository
+
+func (s repositorySet) RepositoryFor(name string) (storage.Repository, bool) {
+	repo, ok := s[name]
+	return repo, ok
+}
+
 func (s *Server) Start() {
+	app := s.buildApp()
+
+	log.Printf("Starting mock API server on port %d", s.options.Port)
+	log.Fatal(app.Listen(fmt.Sprintf(":%d", s.options.Port)))
+}
+
+// buildApp constructs the fiber.App with every route registered, separately
+// from Start's app.Listen so tests can drive it directly via app.Test
+// without binding a port.
+func (s *Server) buildApp() *fiber.App {
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			// Handle jsonapi errors
@@ -84,56 +117,284 @@ func (s *Server) Start() {
 		},
 	})
 
+	// API docs: a generated OpenAPI document and a Swagger UI that points at it
+	app.Get(s.options.APIDocsPrefix+"/swagger.json", s.serveOpenAPIDocs)
+	app.Get(s.options.APIDocsUIPath, s.serveSwaggerUI)
+
+	// Bulk data dump/load, for backing up or seeding a running server. These
+	// endpoints read and write every entity's rows, so a token scoped to a
+	// single entity must not be able to use them: on top of the same
+	// bearer-token auth as entity routes (a no-op when the manifest has no
+	// auth block), they require the adminScope a per-entity token normally
+	// won't hold. Unlike entity scopes (which reject every request when the
+	// manifest has no auth block), the admin scope only applies once an auth
+	// block is present; an auth-less manifest leaves these endpoints open.
+	var adminScopes []string
+	if s.manifest.Auth != nil {
+		adminScopes = []string{adminScope}
+	}
+	adminOnly := requireScopes(adminScopes)
+	app.Get("/_oneapi/export", bearerAuthMiddleware(s.manifest.Auth), adminOnly, s.exportData)
+	app.Post("/_oneapi/import", bearerAuthMiddleware(s.manifest.Auth), adminOnly, s.importData)
+
+	registry := repositorySet(s.repositories)
+
 	// Register API routes based on manifest
 	for entityName, repo := range s.repositories {
-		handler := NewJSONAPIHandler(repo)
+		def := s.manifest.Entities[entityName]
+		entity := s.entities[entityName]
+		handler := NewJSONAPIHandler(entityName, entity, repo, s.entities, registry)
 		entityGroup := app.Group(fmt.Sprintf("/%s", entityName))
+		entityGroup.Use(bearerAuthMiddleware(s.manifest.Auth))
 
-		entityGroup.Get("/", handler.List)
-		entityGroup.Get("/:id", handler.Get)
-		entityGroup.Post("/", handler.Create)
-		entityGroup.Patch("/:id", handler.Update)
-		entityGroup.Delete("/:id", handler.Delete)
+		readScopes := requireScopes(entityScopes(def, "read"))
+		writeScopes := requireScopes(entityScopes(def, "write"))
+		deleteScopes := requireScopes(entityScopes(def, "delete"))
+		strict := s.options.StrictValidation
+
+		entityGroup.Get("/", readScopes, validationMiddleware(entity, operation{responseKind: responseCollection}, strict), handler.List)
+		entityGroup.Get("/:id", readScopes, validationMiddleware(entity, operation{responseKind: responseResource}, strict), handler.Get)
+		entityGroup.Post("/", writeScopes, validationMiddleware(entity, operation{hasBody: true, requireAll: true, responseKind: responseResource}, strict), handler.Create)
+		entityGroup.Patch("/:id", writeScopes, validationMiddleware(entity, operation{hasBody: true, responseKind: responseResource}, strict), handler.Update)
+		entityGroup.Delete("/:id", deleteScopes, validationMiddleware(entity, operation{}, strict), handler.Delete)
+		entityGroup.Get("/:id/relationships/:rel", readScopes, handler.GetRelationship)
+		entityGroup.Patch("/:id/relationships/:rel", writeScopes, handler.ReplaceRelationship)
 	}
 
-	log.Printf("Starting mock API server on port %d", s.options.Port)
-	log.Fatal(app.Listen(fmt.Sprintf(":%d", s.options.Port)))
+	return app
 }
 
-func (s *Server) serveSwaggerUI(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/html")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`<!DOCTYPE html>
-<html lang="en">
-<head>
-    <title>API Docs</title>
-    <link rel="stylesheet" href="https://cdnjs.cloudflare.com/ajax/libs/swagger-ui/5.20.1/swagger-ui.min.css">
-</head>
-<body>
-    <div id="swagger-ui"></div>
-    <script src="https://cdnjs.cloudflare.com/ajax/libs/swagger-ui/5.20.1/swagger-ui-bundle.min.js"></script>
-    <script src="https://cdnjs.cloudflare.com/ajax/libs/swagger-ui/5.20.1/swagger-ui-standalone-preset.min.js"></script>
-    <script>
-        window.onload = function() {
-            const ui = SwaggerUIBundle({
-                url: "` + s.options.APIDocsPrefix + `/swagger.json",
-                dom_id: '#swagger-ui',
-                presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
-                layout: "StandaloneLayout"
-            });
-        };
-    </script>
-</body>
-</html>`))
+func (s *Server) serveSwaggerUI(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.SendString(swaggerUIHTML(s.options.APIDocsPrefix))
 }
 
-func (s *Server) serveOpenAPIDocs(w http.ResponseWriter, r *http.Request) {
-	openAPI := map[string]any{
-		"openapi": "3.0.0",
-		"info": map[string]string{
-			"title":   s.options.APIName,
-			"version": "1.0.0",
-		},
+func (s *Server) serveOpenAPIDocs(c *fiber.Ctx) error {
+	return c.JSON(buildOpenAPISpec(s.manifest, s.options))
+}
+
+// exportData dumps every entity's current rows as a map of entity name to
+// its rows, in the shape /_oneapi/import accepts. Rows keep their "id" key,
+// which the manifest's `seeds:` block and --seed file reject as an unknown
+// field. Each has_many relation is included alongside the row's scalar and
+// belongs_to attributes as a "<relation>_ids" key, so a relationship backed
+// by a join table (rather than a column) round-trips through export/import
+// too.
+func (s *Server) exportData(c *fiber.Ctx) error {
+	dump := make(map[string][]map[string]interface{}, len(s.repositories))
+	registry := repositorySet(s.repositories)
+
+	for entityName, repo := range s.repositories {
+		entity := s.entities[entityName]
+
+		_, total, err := repo.List(storage.Query{Page: 1, PageSize: 1})
+		if err != nil {
+			return err
+		}
+		if total == 0 {
+			dump[entityName] = []map[string]interface{}{}
+			continue
+		}
+
+		rows, _, err := repo.List(storage.Query{Page: 1, PageSize: total})
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			rowID, err := toInt64(row["id"])
+			if err != nil {
+				return err
+			}
+			for relName, rel := range entity.Relations {
+				if rel.Kind != storage.HasMany {
+					continue
+				}
+				related, err := repo.FindRelated(rowID, relName, registry)
+				if err != nil {
+					return err
+				}
+				targetIDs := make([]int64, 0, len(related))
+				for _, targetRow := range related {
+					targetID, err := toInt64(targetRow["id"])
+					if err != nil {
+						return err
+					}
+					targetIDs = append(targetIDs, targetID)
+				}
+				row[relName+"_ids"] = targetIDs
+			}
+		}
+		dump[entityName] = rows
+	}
+
+	return c.JSON(dump)
+}
+
+// importData loads a dump produced by exportData back in, creating each row
+// through the same Create path a JSON:API POST would use, so validation and
+// relationship checks still apply, then replays each has_many "<relation>_ids"
+// key via ReplaceRelated once every entity has been created.
+//
+// Every row gets a freshly assigned id rather than keeping the dump's id, so
+// a belongs_to "<relation>_id" or has_many "<relation>_ids" value is only
+// meaningful as a reference to another row *in the same dump*: importData
+// tracks the dump's old id -> newly assigned id for every row it creates and
+// rewrites both kinds of reference through that mapping. A dump id with no
+// entry in the mapping (an id from outside this dump, e.g. a partial import
+// against an already-populated store) is passed through unchanged on the
+// assumption it already names a row that exists; importData does not attempt
+// to merge a dump into a non-empty store more carefully than that.
+//
+// Rows are created in belongs_to dependency order (the referenced entity
+// before its dependents) rather than the dump's own map iteration order, so
+// a dump doesn't have to be hand-ordered and a belongs_to reference always
+// finds its (remapped) target already created.
+func (s *Server) importData(c *fiber.Ctx) error {
+	var dump map[string][]map[string]interface{}
+	if err := c.BodyParser(&dump); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid import payload")
+	}
+
+	for entityName := range dump {
+		if _, ok := s.repositories[entityName]; !ok {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("import references unknown entity %q", entityName))
+		}
+	}
+
+	// pendingRelation defers a has_many replay until every entity has been
+	// created and idsByEntity is complete, since its target ids may belong
+	// to an entity this owner's own dependency order created after it.
+	type pendingRelation struct {
+		entityName   string
+		ownerNewID   int64
+		relName      string
+		oldTargetIDs []int64
+	}
+	var pending []pendingRelation
+
+	idsByEntity := make(map[string]map[int64]int64, len(dump))
+
+	for _, entityName := range storage.OrderByDependency(s.entities) {
+		rows, ok := dump[entityName]
+		if !ok {
+			continue
+		}
+		repo := s.repositories[entityName]
+		entity := s.entities[entityName]
+		ids := make(map[int64]int64, len(rows))
+
+		for _, row := range rows {
+			data := make(map[string]interface{}, len(row))
+			var rowPending []pendingRelation
+			for k, v := range row {
+				if k == "id" {
+					continue
+				}
+				if relName, ok := strings.CutSuffix(k, "_ids"); ok {
+					if rel, isHasMany := entity.Relations[relName]; isHasMany && rel.Kind == storage.HasMany {
+						targetIDs, err := toInt64Slice(v)
+						if err != nil {
+							return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("%s.%s: %v", entityName, k, err))
+						}
+						rowPending = append(rowPending, pendingRelation{entityName: entityName, relName: relName, oldTargetIDs: targetIDs})
+						continue
+					}
+				}
+				data[k] = v
+			}
+
+			for relName, rel := range entity.Relations {
+				if rel.Kind != storage.BelongsTo {
+					continue
+				}
+				fkKey := relName + "_id"
+				fkValue, exists := data[fkKey]
+				if !exists || fkValue == nil {
+					continue
+				}
+				oldFK, err := toInt64(fkValue)
+				if err != nil {
+					continue
+				}
+				if newFK, ok := idsByEntity[rel.Target][oldFK]; ok {
+					data[fkKey] = newFK
+				}
+			}
+
+			newID, err := repo.Create(data)
+			if err != nil {
+				if strings.Contains(err.Error(), "validation failed") {
+					return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+				}
+				return err
+			}
+
+			if oldID, err := toInt64(row["id"]); err == nil {
+				ids[oldID] = newID
+			}
+			for _, p := range rowPending {
+				p.ownerNewID = newID
+				pending = append(pending, p)
+			}
+		}
+		idsByEntity[entityName] = ids
+	}
+
+	registry := repositorySet(s.repositories)
+	for _, p := range pending {
+		rel := s.entities[p.entityName].Relations[p.relName]
+		newTargetIDs := make([]int64, 0, len(p.oldTargetIDs))
+		for _, oldTargetID := range p.oldTargetIDs {
+			if newTargetID, ok := idsByEntity[rel.Target][oldTargetID]; ok {
+				newTargetIDs = append(newTargetIDs, newTargetID)
+			} else {
+				newTargetIDs = append(newTargetIDs, oldTargetID)
+			}
+		}
+
+		if err := s.repositories[p.entityName].ReplaceRelated(p.ownerNewID, p.relName, newTargetIDs, registry); err != nil {
+			if strings.Contains(err.Error(), "validation failed") {
+				return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+			}
+			return err
+		}
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// toInt64 coerces an id that has round-tripped through JSON (so a float64 or
+// a string, depending on how it was encoded) into an int64.
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case float64:
+		return int64(v), nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	default:
+		return 0, fmt.Errorf("cannot convert %T to an id", value)
+	}
+}
+
+// toInt64Slice coerces a "<relation>_ids" value (a JSON array of ids) into
+// []int64.
+func toInt64Slice(value interface{}) ([]int64, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be an array of ids")
+	}
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		id, err := toInt64(item)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
 	}
-	json.NewEncoder(w).Encode(openAPI)
+	return ids, nil
 }
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/certainty/oneapi/internal/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+// responseKind distinguishes a single-resource response from a collection
+// response, since the two have to be unwrapped differently to validate
+// `data`.
+type responseKind int
+
+const (
+	responseNone responseKind = iota
+	responseResource
+	responseCollection
+)
+
+// operation describes the request/response shape validationMiddleware checks
+// for one route. Callers build one per CRUD route when registering it (see
+// server.go), rather than validationMiddleware resolving it itself: inside a
+// group-level Use middleware, c.Route() still reports the group's own route
+// (e.g. the group prefix, not "/name/" or "/name/:id"), so a route pattern
+// looked up from inside the middleware never matches.
+type operation struct {
+	// hasBody is true for routes that accept a JSON:API request body.
+	hasBody bool
+	// requireAll is true when every required attribute must be present in
+	// the request body (create); false when a partial body is allowed
+	// (update).
+	requireAll   bool
+	responseKind responseKind
+}
+
+// validationMiddleware validates a request's id path parameter and JSON body
+// against entity's schema according to op, rejecting non-conforming requests
+// with a JSON:API error that names the offending field via
+// `source.pointer`/`source.parameter`. When strict is true, it additionally
+// validates the response payload before it reaches the client, replacing it
+// with a 500 rather than sending data that doesn't match its own schema.
+func validationMiddleware(entity *storage.Entity, op operation, strict bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if id := c.Params("id"); id != "" {
+			if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+				return writeValidationErrors(c, []jsonAPIFieldError{{parameter: "id", detail: "id must be an integer"}})
+			}
+		}
+
+		if op.hasBody {
+			var request jsonAPIRequest
+			if err := c.BodyParser(&request); err != nil {
+				return writeValidationErrors(c, []jsonAPIFieldError{{pointer: "/data", detail: "request body must be a valid JSON:API document"}})
+			}
+			if errs := validateAttributes(entity, request.Data.Attributes, op.requireAll); len(errs) > 0 {
+				return writeValidationErrors(c, errs)
+			}
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+		if !strict {
+			return nil
+		}
+		return validateResponseBody(c, entity, op.responseKind)
+	}
+}
+
+// validateAttributes checks data's keys against entity's scalar fields,
+// flagging type mismatches and, when requireAll is true, missing required
+// fields. requireAll is false for partial updates, where an absent field
+// simply keeps its existing value.
+func validateAttributes(entity *storage.Entity, data map[string]interface{}, requireAll bool) []jsonAPIFieldError {
+	var errs []jsonAPIFieldError
+	for name, field := range entity.Fields {
+		if field.Type == "relation" {
+			continue
+		}
+
+		value, exists := data[name]
+		pointer := "/data/attributes/" + name
+
+		if !exists || value == nil {
+			if requireAll && field.Required {
+				errs = append(errs, jsonAPIFieldError{pointer: pointer, detail: fmt.Sprintf("%s is required", name)})
+			}
+			continue
+		}
+
+		if !valueMatchesType(value, field.Type) {
+			errs = append(errs, jsonAPIFieldError{pointer: pointer, detail: fmt.Sprintf("%s must be a %s", name, field.Type)})
+		}
+	}
+	return errs
+}
+
+// valueMatchesType reports whether value is shaped like fieldType once
+// decoded from JSON (so "int" and "double" both arrive as float64).
+func valueMatchesType(value interface{}, fieldType string) bool {
+	switch fieldType {
+	case "string", "enum":
+		_, ok := value.(string)
+		return ok
+	case "int":
+		switch value.(type) {
+		case float64, int, int64:
+			return true
+		default:
+			return false
+		}
+	case "double":
+		_, ok := value.(float64)
+		return ok
+	case "bool":
+		_, ok := value.(bool)
+		return ok
+	default:
+		return true
+	}
+}
+
+// valueMatchesResponseType is valueMatchesType relaxed for values that have
+// round-tripped through storage and JSON encoding rather than arrived as a
+// request body: a bool column stored as SQLite's BOOLEAN comes back as the
+// number 0/1, which json.Unmarshal decodes to float64.
+func valueMatchesResponseType(value interface{}, fieldType string) bool {
+	if fieldType == "bool" {
+		if n, ok := value.(float64); ok {
+			return n == 0 || n == 1
+		}
+	}
+	return valueMatchesType(value, fieldType)
+}
+
+// validateResponseAttributes checks the attributes actually present in a
+// response against entity's schema. Unlike validateAttributes, it never
+// flags an absent field as missing: sparse fieldsets (chunk0-4) legitimately
+// omit required fields from a response, so only fields the handler chose to
+// include are checked.
+func validateResponseAttributes(entity *storage.Entity, data map[string]interface{}) []jsonAPIFieldError {
+	var errs []jsonAPIFieldError
+	for name, field := range entity.Fields {
+		if field.Type == "relation" {
+			continue
+		}
+
+		value, exists := data[name]
+		if !exists || value == nil {
+			continue
+		}
+
+		if !valueMatchesResponseType(value, field.Type) {
+			errs = append(errs, jsonAPIFieldError{pointer: "/data/attributes/" + name, detail: fmt.Sprintf("%s must be a %s", name, field.Type)})
+		}
+	}
+	return errs
+}
+
+// validateResponseBody checks a successful response's `data` against
+// entity's schema, used only when strict validation is enabled.
+func validateResponseBody(c *fiber.Ctx, entity *storage.Entity, kind responseKind) error {
+	if kind == responseNone || c.Response().StatusCode() >= fiber.StatusBadRequest {
+		return nil
+	}
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(c.Response().Body(), &envelope); err != nil || len(envelope.Data) == 0 {
+		return nil
+	}
+
+	var resources []map[string]interface{}
+	switch kind {
+	case responseResource:
+		if string(envelope.Data) == "null" {
+			return nil
+		}
+		var resource map[string]interface{}
+		if err := json.Unmarshal(envelope.Data, &resource); err != nil {
+			return respondServerValidationError(c, "response data was not a JSON:API resource object")
+		}
+		resources = []map[string]interface{}{resource}
+	case responseCollection:
+		if err := json.Unmarshal(envelope.Data, &resources); err != nil {
+			return respondServerValidationError(c, "response data was not a JSON:API resource array")
+		}
+	}
+
+	for _, resource := range resources {
+		attributes, _ := resource["attributes"].(map[string]interface{})
+		if errs := validateResponseAttributes(entity, attributes); len(errs) > 0 {
+			return respondServerValidationError(c, fmt.Sprintf("response attributes failed schema validation: %v", errs))
+		}
+	}
+	return nil
+}
+
+func respondServerValidationError(c *fiber.Ctx, detail string) error {
+	return c.Status(fiber.StatusInternalServerError).JSON(map[string]interface{}{
+		"errors": []map[string]interface{}{
+			{"status": "500", "title": "Response validation failed", "detail": detail},
+		},
+	})
+}
+
+// jsonAPIFieldError is a single request-validation failure, rendered as a
+// JSON:API error object with a `source.pointer` (body attribute) or
+// `source.parameter` (path/query parameter).
+type jsonAPIFieldError struct {
+	pointer   string
+	parameter string
+	detail    string
+}
+
+func (e jsonAPIFieldError) source() map[string]interface{} {
+	if e.pointer != "" {
+		return map[string]interface{}{"pointer": e.pointer}
+	}
+	return map[string]interface{}{"parameter": e.parameter}
+}
+
+func writeValidationErrors(c *fiber.Ctx, errs []jsonAPIFieldError) error {
+	payload := make([]map[string]interface{}, len(errs))
+	for i, e := range errs {
+		payload[i] = map[string]interface{}{
+			"status": "422",
+			"title":  "Validation failed",
+			"detail": e.detail,
+			"source": e.source(),
+		}
+	}
+	return c.Status(fiber.StatusUnprocessableEntity).JSON(map[string]interface{}{"errors": payload})
+}
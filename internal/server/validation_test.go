@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/certainty/oneapi/internal/spec"
+	"github.com/certainty/oneapi/internal/storage"
+)
+
+// newTestServer builds a Server for a single "widgets" entity backed by the
+// memory driver, for tests that only care about request/response plumbing.
+func newTestServer(def spec.EntityDef) *Server {
+	manifest := spec.Manifest{Entities: map[string]spec.EntityDef{"widgets": def}}
+
+	registry := storage.NewEntityRegistry()
+	entity := registry.RegisterEntity("widgets", def)
+	entities := map[string]*storage.Entity{"widgets": entity}
+
+	driver := storage.MemoryDriver{}
+	db, _ := driver.OpenDB(storage.DriverConfig{})
+	repositories := map[string]storage.Repository{"widgets": driver.NewRepository(db, entity)}
+
+	return NewServer(*NewOptions(), manifest, repositories, entities)
+}
+
+// TestValidationMiddlewareRejectsMalformedCreateBody checks that a POST
+// missing a required attribute is rejected by validationMiddleware with a
+// JSON:API 422 naming the offending field, rather than reaching the repo
+// layer and surfacing as Entity.Validate's 422 with no source.pointer.
+func TestValidationMiddlewareRejectsMalformedCreateBody(t *testing.T) {
+	srv := newTestServer(spec.EntityDef{
+		Fields: map[string]spec.FieldDef{
+			"name": {Type: "string", Required: true},
+		},
+	})
+	app := srv.buildApp()
+
+	req := httptest.NewRequest(http.MethodPost, "/widgets/", strings.NewReader(`{"data":{"type":"widgets","attributes":{}}}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusUnprocessableEntity)
+	}
+
+	var body struct {
+		Errors []struct {
+			Status string `json:"status"`
+			Source struct {
+				Pointer string `json:"pointer"`
+			} `json:"source"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(body.Errors) != 1 {
+		t.Fatalf("errors = %v, want exactly one", body.Errors)
+	}
+	if body.Errors[0].Source.Pointer != "/data/attributes/name" {
+		t.Fatalf("source.pointer = %q, want /data/attributes/name", body.Errors[0].Source.Pointer)
+	}
+}
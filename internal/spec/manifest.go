@@ -7,27 +7,78 @@ import (
 )
 
 type ServerConfig struct {
-	Port          *int    `yaml:"port"`
-	HealthCheck   *string `yaml:"healthCheck"`
-	APIDocsPrefix *string `yaml:"apiDocsPrefix"`
-	APIDocsUIPath *string `yaml:"apiDocsUIPath"`
+	Port          *int           `yaml:"port"`
+	HealthCheck   *string        `yaml:"healthCheck"`
+	APIDocsPrefix *string        `yaml:"apiDocsPrefix"`
+	APIDocsUIPath *string        `yaml:"apiDocsUIPath"`
+	Storage       *StorageConfig `yaml:"storage,omitempty"`
+
+	// Persist, if set, is a file path used as the SQLite DSN instead of the
+	// default ":memory:" database, so data survives a process restart. It's
+	// ignored by drivers other than sqlite.
+	Persist *string `yaml:"persist,omitempty"`
+
+	// StrictValidation, if set, overrides the server's default of also
+	// validating response payloads against the entity schema (see
+	// server.Options.StrictValidation).
+	StrictValidation *bool `yaml:"strictValidation,omitempty"`
+}
+
+// StorageConfig selects and configures the storage.Driver used to persist
+// entities. Driver defaults to "sqlite" when the storage block is omitted.
+type StorageConfig struct {
+	Driver  string            `yaml:"driver"`
+	DSN     string            `yaml:"dsn,omitempty"`
+	Options map[string]string `yaml:"options,omitempty"`
 }
 
+// AuthConfig configures bearer-token authentication for the server. If
+// neither BearerToken nor Tokens is set, the server runs with no
+// authentication, which is the default for local development.
 type AuthConfig struct {
-	BearerToken *struct {
-		Token string `yaml:"token"`
-	} `yaml:"bearer_token,omitempty"`
+	// BearerToken is a single unscoped token, valid for every entity/verb.
+	BearerToken *BearerTokenConfig `yaml:"bearer_token,omitempty"`
+
+	// Tokens lists named tokens, each optionally restricted to a set of
+	// scopes. A token with no scopes is valid for every entity/verb, same
+	// as BearerToken.
+	Tokens []TokenConfig `yaml:"tokens,omitempty"`
+}
+
+type BearerTokenConfig struct {
+	Token string `yaml:"token"`
+}
+
+type TokenConfig struct {
+	Token  string   `yaml:"token"`
+	Scopes []string `yaml:"scopes,omitempty"`
+}
+
+// EntityAuthConfig declares the scopes a bearer token must hold to perform
+// reads, writes (create/update) or deletes on an entity. An empty/nil list
+// means the verb requires no scope beyond being authenticated (or nothing at
+// all, if the server has no auth configured).
+type EntityAuthConfig struct {
+	Read   []string `yaml:"read,omitempty"`
+	Write  []string `yaml:"write,omitempty"`
+	Delete []string `yaml:"delete,omitempty"`
 }
 
 type FieldDef struct {
 	Type     string   `yaml:"type"`
 	Required bool     `yaml:"required"`
 	Variants []string `yaml:"variants,omitempty"`
+
+	// Target and Kind apply when Type is "relation": Target names the
+	// related entity and Kind is "belongs_to" or "has_many".
+	Target string `yaml:"target,omitempty"`
+	Kind   string `yaml:"kind,omitempty"`
 }
 
 // EntityDef represents an entity definition in the manifest
 type EntityDef struct {
 	Fields map[string]FieldDef `yaml:"fields"`
+	Auth   *EntityAuthConfig   `yaml:"auth,omitempty"`
 }
 
 type Manifest struct {
@@ -35,6 +86,10 @@ type Manifest struct {
 	Server   *ServerConfig        `yaml:"server"`
 	Auth     *AuthConfig          `yaml:"auth,omitempty"`
 	Entities map[string]EntityDef `yaml:"entities"`
+
+	// Seeds declares fixture rows to insert into each entity's repository
+	// right after schema creation, keyed by entity name.
+	Seeds map[string][]map[string]interface{} `yaml:"seeds,omitempty"`
 }
 
 func LoadManifest(path string) (*Manifest, error) {
@@ -0,0 +1,40 @@
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadSeeds reads a fixture file for the --seed CLI flag, in the same shape
+// as the manifest's `seeds:` block: a map of entity name to the list of
+// attribute rows to insert. The format (YAML or JSON) is chosen by the
+// file's extension.
+func LoadSeeds(path string) (map[string][]map[string]interface{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	seeds := make(map[string][]map[string]interface{})
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.NewDecoder(file).Decode(&seeds); err != nil {
+			return nil, err
+		}
+	case ".yaml", ".yml":
+		if err := yaml.NewDecoder(file).Decode(&seeds); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported seed file extension: %s", path)
+	}
+
+	return seeds, nil
+}
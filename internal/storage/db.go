@@ -2,28 +2,25 @@ package storage
 
 import (
 	"database/sql"
-	_ "github.com/mattn/go-sqlite3"
 )
 
-// DB represents a database connection
+// DB represents a database connection. It is engine-agnostic; the concrete
+// driver (SQLiteDriver, PostgresDriver, ...) decides how it's opened.
 type DB struct {
 	*sql.DB
 }
 
-func NewSQLiteDB() (*DB, error) {
-	db, err := sql.Open("sqlite3", ":memory:")
-	if err != nil {
-		return nil, err
-	}
-
-	if err := db.Ping(); err != nil {
-		return nil, err
-	}
-
-	return &DB{db}, nil
-}
-
 func (db *DB) Exec(query string, args ...any) error {
+	if db.DB == nil {
+		return nil
+	}
 	_, err := db.DB.Exec(query, args...)
 	return err
 }
+
+func (db *DB) Close() error {
+	if db.DB == nil {
+		return nil
+	}
+	return db.DB.Close()
+}
@@ -0,0 +1,70 @@
+package storage
+
+// DriverConfig carries the driver-specific connection options taken from the
+// manifest's storage block.
+type DriverConfig struct {
+	DSN     string
+	Options map[string]string
+}
+
+// Driver abstracts the database-engine-specific parts of the storage layer:
+// opening a connection, mapping manifest field types to SQL types, quoting
+// identifiers, building parameter placeholders and inserting rows. Repository
+// implementations are built on top of a Driver so the same query-building
+// logic in SQLRepository works across engines.
+type Driver interface {
+	// OpenDB opens a connection using the driver-specific configuration.
+	OpenDB(config DriverConfig) (*DB, error)
+	// NewRepository builds a Repository backed by db for the given entity.
+	NewRepository(db *DB, entity *Entity) Repository
+	// QuoteIdent quotes an identifier (table/column name) for safe inclusion
+	// in a query.
+	QuoteIdent(ident string) string
+	// SQLTypeFor maps a field's manifest type to the engine's column type.
+	SQLTypeFor(field Field) string
+	// Placeholder returns the parameter placeholder for the i-th bound value
+	// (1-indexed), e.g. "?" for SQLite, "$1" for Postgres.
+	Placeholder(i int) string
+	// IDColumnDDL returns the column definition for the primary key column
+	// used by CreateSchema, e.g. "id INTEGER PRIMARY KEY AUTOINCREMENT".
+	IDColumnDDL() string
+	// ForeignKeyColumnDDL returns the column definition for a foreign key
+	// column referencing targetTable's id column.
+	ForeignKeyColumnDDL(column, targetTable string) string
+	// Insert inserts a row into table and returns its generated id. Engines
+	// that can't report LastInsertId (e.g. Postgres) use "RETURNING id"
+	// instead, which is why insertion is owned by the driver rather than
+	// SQLRepository.
+	Insert(db *DB, table string, columns []string, values []any) (int64, error)
+}
+
+// drivers holds the set of storage drivers oneapi ships with, keyed by the
+// name used in the manifest's storage.driver field.
+var drivers = map[string]func() Driver{
+	"sqlite":   func() Driver { return SQLiteDriver{} },
+	"postgres": func() Driver { return PostgresDriver{} },
+	"memory":   func() Driver { return MemoryDriver{} },
+}
+
+// DriverFor looks up a registered Driver by name.
+func DriverFor(name string) (Driver, error) {
+	if name == "" {
+		name = "sqlite"
+	}
+
+	ctor, ok := drivers[name]
+	if !ok {
+		return nil, &UnknownDriverError{Name: name}
+	}
+	return ctor(), nil
+}
+
+// UnknownDriverError is returned by DriverFor when the manifest names a
+// storage driver oneapi doesn't know about.
+type UnknownDriverError struct {
+	Name string
+}
+
+func (e *UnknownDriverError) Error() string {
+	return "unknown storage driver: " + e.Name
+}
@@ -15,7 +15,9 @@ type FieldValidator func(any) error
 type Entity struct {
 	Name        string
 	Fields      map[string]Field
+	Relations   map[string]Relation
 	Validations map[string][]FieldValidator
+	Resolver    RelationResolver
 }
 
 // Field represents a field of an entity
@@ -24,12 +26,15 @@ type Field struct {
 	Type     string
 	Required bool
 	Variants []string
+	Target   string
+	Kind     string
 }
 
 func NewEntity(name string, def spec.EntityDef) *Entity {
 	entity := &Entity{
 		Name:        name,
 		Fields:      make(map[string]Field),
+		Relations:   make(map[string]Relation),
 		Validations: make(map[string][]FieldValidator),
 	}
 
@@ -38,6 +43,16 @@ func NewEntity(name string, def spec.EntityDef) *Entity {
 			Type:     field.Type,
 			Required: field.Required,
 			Variants: field.Variants,
+			Target:   field.Target,
+			Kind:     field.Kind,
+		}
+
+		if field.Type == "relation" {
+			entity.Relations[name] = Relation{
+				Name:   name,
+				Target: field.Target,
+				Kind:   RelationKind(field.Kind),
+			}
 		}
 
 		// Create validations for the field
@@ -128,34 +143,25 @@ func NewEntity(name string, def spec.EntityDef) *Entity {
 	return entity
 }
 
-func (e *Entity) GetFieldType(fieldName string) string {
-	field, exists := e.Fields[fieldName]
-	if !exists {
-		return ""
-	}
-
-	switch field.Type {
-	case "string", "enum":
-		return "TEXT"
-	case "int":
-		return "INTEGER"
-	case "double":
-		return "REAL"
-	case "bool":
-		return "BOOLEAN"
-	default:
-		return "TEXT"
-	}
+// SetRelationResolver wires up the lookup Validate uses to confirm that
+// belongs_to foreign keys actually reference an existing row.
+func (e *Entity) SetRelationResolver(resolver RelationResolver) {
+	e.Resolver = resolver
 }
 
 func (e *Entity) Validate(data map[string]any) []error {
 	var errs []error
 
-	// Check for unknown fields
+	// Check for unknown fields, allowing the "<relation>_id" keys that carry
+	// belongs_to foreign keys alongside the declared scalar fields.
 	for fieldName := range data {
-		if _, exists := e.Fields[fieldName]; !exists {
-			errs = append(errs, fmt.Errorf("unknown field: %s", fieldName))
+		if _, exists := e.Fields[fieldName]; exists {
+			continue
+		}
+		if _, isRelationKey := e.relationForForeignKey(fieldName); isRelationKey {
+			continue
 		}
+		errs = append(errs, fmt.Errorf("unknown field: %s", fieldName))
 	}
 
 	// Validate each field
@@ -174,9 +180,51 @@ func (e *Entity) Validate(data map[string]any) []error {
 		}
 	}
 
+	// Validate belongs_to foreign keys reference an existing row.
+	for relName, rel := range e.Relations {
+		if rel.Kind != BelongsTo {
+			continue
+		}
+
+		value, exists := data[relName+"_id"]
+		if !exists || value == nil || e.Resolver == nil {
+			continue
+		}
+
+		id, err := toInt64(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("field %s_id must be an id: %w", relName, err))
+			continue
+		}
+
+		ok, err := e.Resolver(rel.Target, id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("field %s_id could not be validated: %w", relName, err))
+			continue
+		}
+		if !ok {
+			errs = append(errs, fmt.Errorf("field %s_id references a %s that does not exist", relName, rel.Target))
+		}
+	}
+
 	return errs
 }
 
+// relationForForeignKey reports whether fieldName is the "<relation>_id"
+// foreign key column for one of this entity's belongs_to relations.
+func (e *Entity) relationForForeignKey(fieldName string) (Relation, bool) {
+	if !strings.HasSuffix(fieldName, "_id") {
+		return Relation{}, false
+	}
+
+	relName := strings.TrimSuffix(fieldName, "_id")
+	rel, exists := e.Relations[relName]
+	if !exists || rel.Kind != BelongsTo {
+		return Relation{}, false
+	}
+	return rel, true
+}
+
 type EntityRegistry struct {
 	entities map[string]*Entity
 }
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+// PostgresDriver implements Driver for PostgreSQL.
+type PostgresDriver struct{}
+
+func (d PostgresDriver) OpenDB(config DriverConfig) (*DB, error) {
+	if config.DSN == "" {
+		return nil, fmt.Errorf("postgres driver requires a dsn")
+	}
+
+	db, err := sql.Open("postgres", config.DSN)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &DB{db}, nil
+}
+
+func (d PostgresDriver) NewRepository(db *DB, entity *Entity) Repository {
+	return NewSQLRepository(d, db, entity)
+}
+
+func (d PostgresDriver) QuoteIdent(ident string) string {
+	return fmt.Sprintf("%q", ident)
+}
+
+func (d PostgresDriver) SQLTypeFor(field Field) string {
+	switch field.Type {
+	case "string", "enum":
+		return "TEXT"
+	case "int":
+		return "BIGINT"
+	case "double":
+		return "DOUBLE PRECISION"
+	case "bool":
+		return "BOOLEAN"
+	default:
+		return "TEXT"
+	}
+}
+
+func (d PostgresDriver) Placeholder(i int) string {
+	return fmt.Sprintf("$%d", i)
+}
+
+func (d PostgresDriver) IDColumnDDL() string {
+	return "id BIGSERIAL PRIMARY KEY"
+}
+
+func (d PostgresDriver) ForeignKeyColumnDDL(column, targetTable string) string {
+	return fmt.Sprintf("%s BIGINT REFERENCES %s(id)", d.QuoteIdent(column), d.QuoteIdent(targetTable))
+}
+
+// Insert uses "RETURNING id" since Postgres' database/sql driver doesn't
+// support LastInsertId.
+func (d PostgresDriver) Insert(db *DB, table string, columns []string, values []any) (int64, error) {
+	quotedColumns := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, c := range columns {
+		quotedColumns[i] = d.QuoteIdent(c)
+		placeholders[i] = d.Placeholder(i + 1)
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING id",
+		d.QuoteIdent(table), strings.Join(quotedColumns, ", "), strings.Join(placeholders, ", "))
+
+	var id int64
+	if err := db.QueryRow(query, values...).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
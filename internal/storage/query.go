@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FilterOp is a comparison operator usable in a `filter[field][op]` query
+// parameter.
+type FilterOp string
+
+const (
+	FilterEq   FilterOp = "eq"
+	FilterGt   FilterOp = "gt"
+	FilterGte  FilterOp = "gte"
+	FilterLt   FilterOp = "lt"
+	FilterLte  FilterOp = "lte"
+	FilterLike FilterOp = "like"
+)
+
+// Filter is a single `filter[field]` (implicitly "eq") or `filter[field][op]`
+// query constraint.
+type Filter struct {
+	Field string
+	Op    FilterOp
+	Value string
+}
+
+// Sort is a single `sort=` term; Descending is set by a leading "-".
+type Sort struct {
+	Field      string
+	Descending bool
+}
+
+// Query carries the parsed JSON:API query-string conventions Repository.List
+// supports: pagination, filtering, sorting and sparse fieldsets. Field names
+// are validated by the caller (JSONAPIHandler) against the entity before
+// reaching a driver, so drivers only need to quote/parameterize them safely.
+type Query struct {
+	Page     int
+	PageSize int
+	Filters  []Filter
+	Sorts    []Sort
+	// Fields selects a sparse fieldset; empty means all fields.
+	Fields []string
+}
+
+// matchesFilters reports whether row satisfies every filter, used by
+// MemoryRepository which has no SQL engine to push filtering down to.
+func matchesFilters(row map[string]any, filters []Filter) bool {
+	for _, f := range filters {
+		if !matchesFilter(row[f.Field], f) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesFilter(value any, f Filter) bool {
+	if f.Op == FilterLike {
+		s := fmt.Sprintf("%v", value)
+		pattern := strings.Trim(f.Value, "%")
+		return strings.Contains(s, pattern)
+	}
+
+	cmp := compareAny(value, f.Value)
+	switch f.Op {
+	case FilterEq:
+		return cmp == 0
+	case FilterGt:
+		return cmp > 0
+	case FilterGte:
+		return cmp >= 0
+	case FilterLt:
+		return cmp < 0
+	case FilterLte:
+		return cmp <= 0
+	default:
+		return false
+	}
+}
+
+// sortRows sorts rows in place according to sorts, in priority order.
+func sortRows(rows []map[string]any, sorts []Sort) {
+	if len(sorts) == 0 {
+		return
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, s := range sorts {
+			cmp := compareAny(rows[i][s.Field], rows[j][s.Field])
+			if cmp == 0 {
+				continue
+			}
+			if s.Descending {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+// projectFields returns a copy of row containing only id plus the named
+// fields, or row unchanged if fields is empty.
+func projectFields(row map[string]any, fields []string) map[string]any {
+	if len(fields) == 0 {
+		return row
+	}
+	projected := map[string]any{"id": row["id"]}
+	for _, f := range fields {
+		if v, ok := row[f]; ok {
+			projected[f] = v
+		}
+	}
+	return projected
+}
+
+// compareAny compares two values numerically when both can be parsed as
+// numbers, otherwise falls back to a string comparison.
+func compareAny(a, b any) int {
+	af, aok := toFloat(a)
+	bf, bok := toFloat(b)
+	if aok && bok {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+func toFloat(v any) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case int64:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// RelationKind identifies how two entities relate to each other, as declared
+// by a `type: relation` field's `kind` in the manifest.
+type RelationKind string
+
+const (
+	BelongsTo RelationKind = "belongs_to"
+	HasMany   RelationKind = "has_many"
+)
+
+// Relation describes a `type: relation` field on an Entity: which entity it
+// points at and whether this side holds the foreign key (belongs_to) or the
+// other side does (has_many).
+type Relation struct {
+	Name   string
+	Target string
+	Kind   RelationKind
+}
+
+// RelationResolver checks whether an instance of targetEntity with the given
+// id exists. Entity.Validate uses it to reject belongs_to foreign keys that
+// don't point at anything.
+type RelationResolver func(targetEntity string, id int64) (bool, error)
+
+// RelationRegistry resolves the Repository for a given entity name. It lets a
+// Repository look up its related entities' repositories without holding
+// direct references to them, since repositories are all constructed
+// independently in main.
+type RelationRegistry interface {
+	RepositoryFor(entityName string) (Repository, bool)
+}
+
+// OrderByDependency returns entity names ordered so that every entity
+// appears after the targets of its belongs_to relations, making it safe to
+// insert seed/import rows in the returned order without tripping the
+// FK-existence check in Entity.Validate. Entities are otherwise ordered by
+// name, so the result is deterministic across runs (unlike ranging a map
+// directly). A belongs_to cycle breaks ties in name order rather than
+// looping forever.
+func OrderByDependency(entities map[string]*Entity) []string {
+	names := make([]string, 0, len(entities))
+	for name := range entities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(names))
+	order := make([]string, 0, len(names))
+
+	var visit func(name string)
+	visit = func(name string) {
+		if state[name] != unvisited {
+			return
+		}
+		state[name] = visiting
+
+		entity, ok := entities[name]
+		if ok {
+			targets := make([]string, 0, len(entity.Relations))
+			for _, rel := range entity.Relations {
+				if rel.Kind == BelongsTo && rel.Target != name {
+					targets = append(targets, rel.Target)
+				}
+			}
+			sort.Strings(targets)
+			for _, target := range targets {
+				if state[target] != visiting {
+					visit(target)
+				}
+			}
+		}
+
+		state[name] = done
+		order = append(order, name)
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return order
+}
+
+// toInt64 coerces a foreign key value coming from either a manifest/JSON
+// payload or a driver row scan into an int64 id.
+func toInt64(value any) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case float64:
+		return int64(v), nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	default:
+		return 0, fmt.Errorf("cannot convert %T to an id", value)
+	}
+}
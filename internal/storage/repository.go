@@ -4,76 +4,159 @@ import (
 	"database/sql"
 	"fmt"
 	"maps"
+	"sort"
 	"strings"
 )
 
 type Repository interface {
 	CreateSchema() error
-	List(page, pageSize int) ([]map[string]any, int, error)
+
+	// List returns a page of rows matching q's filters, in q's sort order,
+	// projected to q's sparse fieldset, along with the total row count
+	// matching the filters (ignoring pagination).
+	List(q Query) ([]map[string]any, int, error)
 	FindByID(id int64) (map[string]any, error)
 	Create(data map[string]any) (int64, error)
 	Update(id int64, data map[string]any) error
 	Delete(id int64) error
+
+	// FindRelated resolves the related row(s) for relationName on the entity
+	// identified by id, using registry to look up the target entity's
+	// Repository.
+	FindRelated(id int64, relationName string, registry RelationRegistry) ([]map[string]any, error)
+
+	// ReplaceRelated replaces the relationship for relationName on the
+	// entity identified by id with targetIDs, using registry to look up the
+	// target entity's Repository. For a belongs_to relation targetIDs must
+	// contain zero or one id; for has_many it replaces the full set of
+	// related ids. Every id in targetIDs must reference an existing target
+	// row.
+	ReplaceRelated(id int64, relationName string, targetIDs []int64, registry RelationRegistry) error
 }
 
-// SQLiteRepository implements Repository for SQLite
-type SQLiteRepository struct {
+// SQLRepository implements Repository on top of database/sql. It delegates
+// every engine-specific concern (identifier quoting, column types, parameter
+// placeholders, and how an inserted row's id is obtained) to a Driver, so the
+// same query-building logic works across SQLite, Postgres, etc.
+type SQLRepository struct {
+	driver Driver
 	db     *DB
 	entity *Entity
 }
 
-// NewSQLiteRepository creates a new SQLite repository
-func NewSQLiteRepository(db *DB, entity *Entity) *SQLiteRepository {
-	return &SQLiteRepository{
+// NewSQLRepository creates a new SQL-backed repository for entity, using
+// driver to generate engine-specific SQL.
+func NewSQLRepository(driver Driver, db *DB, entity *Entity) *SQLRepository {
+	return &SQLRepository{
+		driver: driver,
 		db:     db,
 		entity: entity,
 	}
 }
 
-func (r *SQLiteRepository) CreateSchema() error {
-	var columns []string
-	columns = append(columns, "id INTEGER PRIMARY KEY AUTOINCREMENT")
+func (r *SQLRepository) CreateSchema() error {
+	columns := []string{r.driver.IDColumnDDL()}
+
+	for _, fieldName := range r.sortedFieldNames() {
+		field := r.entity.Fields[fieldName]
+		if field.Type == "relation" {
+			continue
+		}
 
-	for fieldName, field := range r.entity.Fields {
-		sqlType := r.entity.GetFieldType(fieldName)
+		sqlType := r.driver.SQLTypeFor(field)
 		if sqlType == "" {
 			continue
 		}
 
-		column := fmt.Sprintf("%s %s", fieldName, sqlType)
+		column := fmt.Sprintf("%s %s", r.driver.QuoteIdent(fieldName), sqlType)
 		if field.Required {
 			column += " NOT NULL"
 		}
 		columns = append(columns, column)
 	}
 
+	for _, relName := range r.sortedRelationNames() {
+		rel := r.entity.Relations[relName]
+		if rel.Kind == BelongsTo {
+			columns = append(columns, r.driver.ForeignKeyColumnDDL(relName+"_id", rel.Target))
+		}
+	}
+
 	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)",
-		r.entity.Name, strings.Join(columns, ", "))
+		r.driver.QuoteIdent(r.entity.Name), strings.Join(columns, ", "))
+	if err := r.db.Exec(query); err != nil {
+		return err
+	}
+
+	for _, relName := range r.sortedRelationNames() {
+		rel := r.entity.Relations[relName]
+		if rel.Kind != HasMany {
+			continue
+		}
+		if err := r.createJoinTable(relName, rel); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createJoinTable ensures the join table backing a has_many relation exists.
+// It's created from the owning side, named "<entity>_<relation>", with one
+// foreign key column pointing back at this entity and one at the target.
+func (r *SQLRepository) createJoinTable(relName string, rel Relation) error {
+	ownerColumn := r.entity.Name + "_id"
+	targetColumn := rel.Target + "_id"
+
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s, %s)",
+		r.driver.QuoteIdent(r.joinTableName(relName)),
+		r.driver.ForeignKeyColumnDDL(ownerColumn, r.entity.Name),
+		r.driver.ForeignKeyColumnDDL(targetColumn, rel.Target))
 
 	return r.db.Exec(query)
 }
 
-func (r *SQLiteRepository) List(page, pageSize int) ([]map[string]any, int, error) {
+func (r *SQLRepository) joinTableName(relName string) string {
+	return fmt.Sprintf("%s_%s", r.entity.Name, relName)
+}
+
+func (r *SQLRepository) List(q Query) ([]map[string]any, int, error) {
+	page := q.Page
 	if page < 1 {
 		page = 1
 	}
+	pageSize := q.PageSize
 	if pageSize < 1 {
 		pageSize = 10
 	}
 
 	offset := (page - 1) * pageSize
+	table := r.driver.QuoteIdent(r.entity.Name)
+
+	whereClause, whereArgs, err := r.buildWhereClause(q.Filters)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	// Count total rows
+	// Count total rows matching the filters
 	var total int
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", r.entity.Name)
-	err := r.db.QueryRow(countQuery).Scan(&total)
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", table, whereClause)
+	if err := r.db.QueryRow(countQuery, whereArgs...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	orderClause, err := r.buildOrderClause(q.Sorts)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	// Fetch paginated data
-	query := fmt.Sprintf("SELECT * FROM %s LIMIT ? OFFSET ?", r.entity.Name)
-	rows, err := r.db.Query(query, pageSize, offset)
+	limitPlaceholder := r.driver.Placeholder(len(whereArgs) + 1)
+	offsetPlaceholder := r.driver.Placeholder(len(whereArgs) + 2)
+	query := fmt.Sprintf("SELECT %s FROM %s%s%s LIMIT %s OFFSET %s",
+		r.selectColumns(q.Fields), table, whereClause, orderClause, limitPlaceholder, offsetPlaceholder)
+
+	args := append(append([]any{}, whereArgs...), pageSize, offset)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -86,54 +169,96 @@ func (r *SQLiteRepository) List(page, pageSize int) ([]map[string]any, int, erro
 
 	result := make([]map[string]any, 0)
 	for rows.Next() {
-		// Create a slice of interface{} to hold the values
-		values := make([]any, len(columns))
-		valuePtrs := make([]any, len(columns))
-		for i := range values {
-			valuePtrs[i] = &values[i]
+		entry, err := scanRow(rows, columns)
+		if err != nil {
+			return nil, 0, err
 		}
+		result = append(result, entry)
+	}
 
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, 0, err
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+// buildWhereClause turns filters into a parameterized " WHERE ..." clause
+// (or "" if there are none) plus its bound arguments. Field names are quoted
+// as identifiers and values are always passed as parameters, never
+// string-concatenated into the query.
+func (r *SQLRepository) buildWhereClause(filters []Filter) (string, []any, error) {
+	if len(filters) == 0 {
+		return "", nil, nil
+	}
+
+	conditions := make([]string, 0, len(filters))
+	args := make([]any, 0, len(filters))
+	for i, f := range filters {
+		sqlOp, ok := filterSQLOperators[f.Op]
+		if !ok {
+			return "", nil, fmt.Errorf("unsupported filter operator %q", f.Op)
 		}
+		conditions = append(conditions, fmt.Sprintf("%s %s %s", r.driver.QuoteIdent(f.Field), sqlOp, r.driver.Placeholder(i+1)))
+		args = append(args, f.Value)
+	}
 
-		// Create a map to hold the row data
-		entry := make(map[string]any)
-		for i, col := range columns {
-			val := values[i]
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
 
-			// Handle nil values
-			if val == nil {
-				entry[col] = nil
-				continue
-			}
+// buildOrderClause turns sorts into a " ORDER BY ..." clause, or "" if there
+// are none.
+func (r *SQLRepository) buildOrderClause(sorts []Sort) (string, error) {
+	if len(sorts) == 0 {
+		return "", nil
+	}
 
-			// Convert to appropriate type
-			switch v := val.(type) {
-			case []byte:
-				entry[col] = string(v)
-			default:
-				entry[col] = v
-			}
+	terms := make([]string, 0, len(sorts))
+	for _, s := range sorts {
+		direction := "ASC"
+		if s.Descending {
+			direction = "DESC"
 		}
+		terms = append(terms, fmt.Sprintf("%s %s", r.driver.QuoteIdent(s.Field), direction))
+	}
+	return " ORDER BY " + strings.Join(terms, ", "), nil
+}
 
-		result = append(result, entry)
+// selectColumns builds the SELECT column list for a sparse fieldset, always
+// including "id", or "*" if no fieldset was requested.
+func (r *SQLRepository) selectColumns(fields []string) string {
+	if len(fields) == 0 {
+		return "*"
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, 0, err
+	columns := make([]string, 0, len(fields)+1)
+	columns = append(columns, r.driver.QuoteIdent("id"))
+	for _, f := range fields {
+		columns = append(columns, r.driver.QuoteIdent(f))
 	}
+	return strings.Join(columns, ", ")
+}
 
-	return result, total, nil
+// filterSQLOperators maps a FilterOp onto its SQL operator.
+var filterSQLOperators = map[FilterOp]string{
+	FilterEq:   "=",
+	FilterGt:   ">",
+	FilterGte:  ">=",
+	FilterLt:   "<",
+	FilterLte:  "<=",
+	FilterLike: "LIKE",
 }
 
 // FindByID retrieves an entity by ID
-func (r *SQLiteRepository) FindByID(id int64) (map[string]any, error) {
-	query := fmt.Sprintf("SELECT * FROM %s WHERE id = ?", r.entity.Name)
+func (r *SQLRepository) FindByID(id int64) (map[string]any, error) {
+	table := r.driver.QuoteIdent(r.entity.Name)
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s = %s",
+		table, r.driver.QuoteIdent("id"), r.driver.Placeholder(1))
 	row := r.db.QueryRow(query, id)
 
 	// Get columns
-	rows, err := r.db.Query(fmt.Sprintf("SELECT * FROM %s LIMIT 1", r.entity.Name))
+	rows, err := r.db.Query(fmt.Sprintf("SELECT * FROM %s LIMIT 1", table))
 	if err != nil {
 		return nil, err
 	}
@@ -143,7 +268,6 @@ func (r *SQLiteRepository) FindByID(id int64) (map[string]any, error) {
 		return nil, err
 	}
 
-	// Create a slice of interface{} to hold the values
 	values := make([]any, len(columns))
 	valuePtrs := make([]any, len(columns))
 	for i := range values {
@@ -157,74 +281,32 @@ func (r *SQLiteRepository) FindByID(id int64) (map[string]any, error) {
 		return nil, err
 	}
 
-	// Create a map to hold the row data
-	result := make(map[string]any)
-	for i, col := range columns {
-		val := values[i]
-
-		// Handle nil values
-		if val == nil {
-			result[col] = nil
-			continue
-		}
-
-		// Convert to appropriate type
-		switch v := val.(type) {
-		case []byte:
-			result[col] = string(v)
-		default:
-			result[col] = v
-		}
-	}
-
-	return result, nil
+	return rowFromValues(columns, values), nil
 }
 
 // Create creates a new entity
-func (r *SQLiteRepository) Create(data map[string]any) (int64, error) {
-	// Validate data
+func (r *SQLRepository) Create(data map[string]any) (int64, error) {
 	errs := r.entity.Validate(data)
 	if len(errs) > 0 {
 		return 0, fmt.Errorf("validation failed: %v", errs)
 	}
 
-	// Prepare SQL fields and values
 	var fields []string
-	var placeholders []string
 	var values []any
-
-	for fieldName, value := range data {
-		// Skip if the field doesn't exist in the entity
-		if _, exists := r.entity.Fields[fieldName]; !exists {
+	for _, columnName := range r.writableColumnNames() {
+		value, exists := data[columnName]
+		if !exists {
 			continue
 		}
-
-		fields = append(fields, fieldName)
-		placeholders = append(placeholders, "?")
+		fields = append(fields, columnName)
 		values = append(values, value)
 	}
 
-	// Execute query
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		r.entity.Name, strings.Join(fields, ", "), strings.Join(placeholders, ", "))
-
-	result, err := r.db.DB.Exec(query, values...)
-	if err != nil {
-		return 0, err
-	}
-
-	return result.LastInsertId()
+	return r.driver.Insert(r.db, r.entity.Name, fields, values)
 }
 
 // Update updates an existing entity
-func (r *SQLiteRepository) Update(id int64, data map[string]any) error {
-	// Check if entity exists
-	_, err := r.FindByID(id)
-	if err != nil {
-		return err
-	}
-
-	// Get existing data to merge with update data
+func (r *SQLRepository) Update(id int64, data map[string]any) error {
 	existing, err := r.FindByID(id)
 	if err != nil {
 		return err
@@ -239,46 +321,271 @@ func (r *SQLiteRepository) Update(id int64, data map[string]any) error {
 	}
 	maps.Copy(merged, data)
 
-	// Validate merged data
 	errs := r.entity.Validate(merged)
 	if len(errs) > 0 {
 		return fmt.Errorf("validation failed: %v", errs)
 	}
 
-	// Prepare SQL fields and values for update
 	var setClause []string
 	var values []any
-
-	for fieldName, value := range data {
-		// Skip if the field doesn't exist in the entity
-		if _, exists := r.entity.Fields[fieldName]; !exists {
+	i := 1
+	for _, columnName := range r.writableColumnNames() {
+		value, exists := data[columnName]
+		if !exists {
 			continue
 		}
-
-		setClause = append(setClause, fmt.Sprintf("%s = ?", fieldName))
+		setClause = append(setClause, fmt.Sprintf("%s = %s", r.driver.QuoteIdent(columnName), r.driver.Placeholder(i)))
 		values = append(values, value)
+		i++
 	}
-
-	// Add ID for the WHERE clause
 	values = append(values, id)
 
-	// Execute query
-	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = ?",
-		r.entity.Name, strings.Join(setClause, ", "))
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s",
+		r.driver.QuoteIdent(r.entity.Name), strings.Join(setClause, ", "),
+		r.driver.QuoteIdent("id"), r.driver.Placeholder(i))
 
 	_, err = r.db.DB.Exec(query, values...)
 	return err
 }
 
 // Delete deletes an entity
-func (r *SQLiteRepository) Delete(id int64) error {
-	// Check if entity exists
-	_, err := r.FindByID(id)
-	if err != nil {
+func (r *SQLRepository) Delete(id int64) error {
+	if _, err := r.FindByID(id); err != nil {
 		return err
 	}
 
-	query := fmt.Sprintf("DELETE FROM %s WHERE id = ?", r.entity.Name)
-	_, err = r.db.DB.Exec(query, id)
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = %s",
+		r.driver.QuoteIdent(r.entity.Name), r.driver.QuoteIdent("id"), r.driver.Placeholder(1))
+	_, err := r.db.DB.Exec(query, id)
 	return err
 }
+
+func (r *SQLRepository) sortedFieldNames() []string {
+	names := make([]string, 0, len(r.entity.Fields))
+	for name := range r.entity.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *SQLRepository) sortedRelationNames() []string {
+	names := make([]string, 0, len(r.entity.Relations))
+	for name := range r.entity.Relations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writableColumnNames lists the real table columns Create/Update may set:
+// every scalar field plus the "<relation>_id" foreign key column for each
+// belongs_to relation.
+func (r *SQLRepository) writableColumnNames() []string {
+	var names []string
+	for _, fieldName := range r.sortedFieldNames() {
+		if r.entity.Fields[fieldName].Type == "relation" {
+			continue
+		}
+		names = append(names, fieldName)
+	}
+	for _, relName := range r.sortedRelationNames() {
+		if r.entity.Relations[relName].Kind == BelongsTo {
+			names = append(names, relName+"_id")
+		}
+	}
+	return names
+}
+
+// FindRelated resolves relationName for the row identified by id: the single
+// related row for belongs_to, or every row joined through the relation's
+// join table for has_many.
+func (r *SQLRepository) FindRelated(id int64, relationName string, registry RelationRegistry) ([]map[string]any, error) {
+	rel, ok := r.entity.Relations[relationName]
+	if !ok {
+		return nil, fmt.Errorf("entity %s has no relation %q", r.entity.Name, relationName)
+	}
+
+	targetRepo, ok := registry.RepositoryFor(rel.Target)
+	if !ok {
+		return nil, fmt.Errorf("unknown related entity %q", rel.Target)
+	}
+
+	switch rel.Kind {
+	case BelongsTo:
+		row, err := r.FindByID(id)
+		if err != nil {
+			return nil, err
+		}
+		fkValue := row[relationName+"_id"]
+		if fkValue == nil {
+			return []map[string]any{}, nil
+		}
+		fkID, err := toInt64(fkValue)
+		if err != nil {
+			return nil, err
+		}
+		related, err := targetRepo.FindByID(fkID)
+		if err != nil {
+			return nil, err
+		}
+		return []map[string]any{related}, nil
+
+	case HasMany:
+		if _, err := r.FindByID(id); err != nil {
+			return nil, err
+		}
+
+		targetIDs, err := r.joinedIDs(id, relationName, rel)
+		if err != nil {
+			return nil, err
+		}
+
+		related := make([]map[string]any, 0, len(targetIDs))
+		for _, targetID := range targetIDs {
+			row, err := targetRepo.FindByID(targetID)
+			if err != nil {
+				return nil, err
+			}
+			related = append(related, row)
+		}
+		return related, nil
+
+	default:
+		return nil, fmt.Errorf("relation %q has unknown kind %q", relationName, rel.Kind)
+	}
+}
+
+// ReplaceRelated replaces the relationship for relationName on the row
+// identified by id.
+func (r *SQLRepository) ReplaceRelated(id int64, relationName string, targetIDs []int64, registry RelationRegistry) error {
+	rel, ok := r.entity.Relations[relationName]
+	if !ok {
+		return fmt.Errorf("entity %s has no relation %q", r.entity.Name, relationName)
+	}
+
+	switch rel.Kind {
+	case BelongsTo:
+		if len(targetIDs) == 0 {
+			return r.Update(id, map[string]any{relationName + "_id": nil})
+		}
+		if len(targetIDs) > 1 {
+			return fmt.Errorf("relation %q is belongs_to and accepts at most one id", relationName)
+		}
+		return r.Update(id, map[string]any{relationName + "_id": targetIDs[0]})
+
+	case HasMany:
+		if _, err := r.FindByID(id); err != nil {
+			return err
+		}
+		if errs := validateTargetIDsExist(rel, targetIDs, registry); len(errs) > 0 {
+			return fmt.Errorf("validation failed: %v", errs)
+		}
+
+		joinTable := r.joinTableName(relationName)
+		ownerColumn := r.entity.Name + "_id"
+		targetColumn := rel.Target + "_id"
+
+		deleteQuery := fmt.Sprintf("DELETE FROM %s WHERE %s = %s",
+			r.driver.QuoteIdent(joinTable), r.driver.QuoteIdent(ownerColumn), r.driver.Placeholder(1))
+		if err := r.db.Exec(deleteQuery, id); err != nil {
+			return err
+		}
+
+		// The join table has no id column, so rows go in through a plain
+		// INSERT rather than driver.Insert (which reads back a generated id).
+		insertQuery := fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (%s, %s)",
+			r.driver.QuoteIdent(joinTable), r.driver.QuoteIdent(ownerColumn), r.driver.QuoteIdent(targetColumn),
+			r.driver.Placeholder(1), r.driver.Placeholder(2))
+		for _, targetID := range targetIDs {
+			if err := r.db.Exec(insertQuery, id, targetID); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("relation %q has unknown kind %q", relationName, rel.Kind)
+	}
+}
+
+// validateTargetIDsExist checks that every id in targetIDs names an existing
+// row in rel.Target's repository, so a has_many ReplaceRelated can't create
+// join rows pointing at nothing (SQLite has foreign keys off by default, so
+// nothing else would catch this).
+func validateTargetIDsExist(rel Relation, targetIDs []int64, registry RelationRegistry) []error {
+	targetRepo, ok := registry.RepositoryFor(rel.Target)
+	if !ok {
+		return []error{fmt.Errorf("unknown related entity %q", rel.Target)}
+	}
+
+	var errs []error
+	for _, targetID := range targetIDs {
+		if _, err := targetRepo.FindByID(targetID); err != nil {
+			errs = append(errs, fmt.Errorf("%s id %d does not exist", rel.Target, targetID))
+		}
+	}
+	return errs
+}
+
+// joinedIDs returns the target ids currently joined to id through a has_many
+// relation's join table.
+func (r *SQLRepository) joinedIDs(id int64, relationName string, rel Relation) ([]int64, error) {
+	joinTable := r.joinTableName(relationName)
+	ownerColumn := r.entity.Name + "_id"
+	targetColumn := rel.Target + "_id"
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s",
+		r.driver.QuoteIdent(targetColumn), r.driver.QuoteIdent(joinTable),
+		r.driver.QuoteIdent(ownerColumn), r.driver.Placeholder(1))
+
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var targetID int64
+		if err := rows.Scan(&targetID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, targetID)
+	}
+	return ids, rows.Err()
+}
+
+// scanRow scans the current row of rows into a map keyed by column name.
+func scanRow(rows *sql.Rows, columns []string) (map[string]any, error) {
+	values := make([]any, len(columns))
+	valuePtrs := make([]any, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	if err := rows.Scan(valuePtrs...); err != nil {
+		return nil, err
+	}
+
+	return rowFromValues(columns, values), nil
+}
+
+func rowFromValues(columns []string, values []any) map[string]any {
+	entry := make(map[string]any, len(columns))
+	for i, col := range columns {
+		val := values[i]
+		if val == nil {
+			entry[col] = nil
+			continue
+		}
+
+		switch v := val.(type) {
+		case []byte:
+			entry[col] = string(v)
+		default:
+			entry[col] = v
+		}
+	}
+	return entry
+}
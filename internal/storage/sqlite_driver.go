@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteDriver implements Driver for SQLite, the default engine used when no
+// storage block is configured in the manifest.
+type SQLiteDriver struct{}
+
+func (d SQLiteDriver) OpenDB(config DriverConfig) (*DB, error) {
+	dsn := config.DSN
+	if dsn == "" {
+		dsn = ":memory:"
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &DB{db}, nil
+}
+
+func (d SQLiteDriver) NewRepository(db *DB, entity *Entity) Repository {
+	return NewSQLRepository(d, db, entity)
+}
+
+func (d SQLiteDriver) QuoteIdent(ident string) string {
+	return fmt.Sprintf("%q", ident)
+}
+
+func (d SQLiteDriver) SQLTypeFor(field Field) string {
+	switch field.Type {
+	case "string", "enum":
+		return "TEXT"
+	case "int":
+		return "INTEGER"
+	case "double":
+		return "REAL"
+	case "bool":
+		return "BOOLEAN"
+	default:
+		return "TEXT"
+	}
+}
+
+func (d SQLiteDriver) Placeholder(i int) string {
+	return "?"
+}
+
+func (d SQLiteDriver) IDColumnDDL() string {
+	return "id INTEGER PRIMARY KEY AUTOINCREMENT"
+}
+
+func (d SQLiteDriver) ForeignKeyColumnDDL(column, targetTable string) string {
+	return fmt.Sprintf("%s INTEGER REFERENCES %s(id)", d.QuoteIdent(column), d.QuoteIdent(targetTable))
+}
+
+func (d SQLiteDriver) Insert(db *DB, table string, columns []string, values []any) (int64, error) {
+	quotedColumns := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, c := range columns {
+		quotedColumns[i] = d.QuoteIdent(c)
+		placeholders[i] = d.Placeholder(i + 1)
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		d.QuoteIdent(table), strings.Join(quotedColumns, ", "), strings.Join(placeholders, ", "))
+
+	result, err := db.DB.Exec(query, values...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}